@@ -24,26 +24,40 @@ import (
 )
 
 type application struct {
-	config         config
-	logger         *zap.SugaredLogger
-	rateLimiter    ratelimiter.Limiter
-	storage        *mongo.Storage
-	broker         queue.Broker
-	menuRepo       repo.MenuRepository
-	parsingService *service.ParsingService
-	productService *service.ProductService
-	menuWorker     *worker.MenuParsingWorker
-	productWorker  *worker.ProductStatusWorker
+	config          config
+	logger          *zap.SugaredLogger
+	rateLimiter     ratelimiter.Limiter
+	storage         *mongo.Storage
+	broker          queue.Broker
+	menuRepo        repo.MenuRepository
+	parsingService  *service.ParsingService
+	productService  *service.ProductService
+	menuService     *service.MenuService
+	branchService   *service.BranchService
+	auditLogService *service.AuditLogService
+	webhookService  *service.WebhookService
+	menuWorker      *worker.MenuParsingWorker
+	productWorker   *worker.ProductStatusWorker
+	purgeWorker     *worker.MenuPurgeWorker
+	restoreWorker   *worker.RestoreScheduler
+	archiveWorker   *worker.AuditArchiveWorker
+	outboxRelay     *worker.OutboxRelay
+	webhookWorker   *worker.WebhookDispatcher
 }
 
 type config struct {
-	addr        string
-	env         string
-	apiURL      string
-	rateLimiter ratelimiter.Config
-	mongo       mongoConfig
-	rabbitMQ    rabbitMQConfig
-	googleCreds string
+	addr         string
+	env          string
+	apiURL       string
+	rateLimiter  ratelimiter.Config
+	mongo        mongoConfig
+	rabbitMQ     rabbitMQConfig
+	menuPurge    menuPurgeConfig
+	restores     restoresConfig
+	auditArchive auditArchiveConfig
+	outbox       outboxConfig
+	webhooks     webhooksConfig
+	googleCreds  string
 }
 
 type mongoConfig struct {
@@ -52,6 +66,32 @@ type mongoConfig struct {
 	Timeout  time.Duration
 }
 
+type menuPurgeConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+}
+
+type restoresConfig struct {
+	Interval time.Duration
+}
+
+type auditArchiveConfig struct {
+	Retention time.Duration
+	Interval  time.Duration
+	Dir       string
+}
+
+type outboxConfig struct {
+	Interval time.Duration
+}
+
+type webhooksConfig struct {
+	Interval    time.Duration
+	Timeout     time.Duration
+	MaxAttempts int
+	Concurrency int
+}
+
 type rabbitMQConfig struct {
 	URL           string
 	MaxRetries    int
@@ -74,8 +114,58 @@ func (app *application) mount() http.Handler {
 		r.Get("/parse/{task_id}", app.getParseTaskHandler)
 
 		r.Get("/menu/{menu_id}", app.getMenuHandler)
+		r.Delete("/menus/{menu_id}", app.deleteMenuHandler)
+		r.Post("/menus/{menu_id}/restore", app.restoreMenuHandler)
+
+		r.Post("/menus/{menu_id}/quote", app.quoteHandler)
+
+		r.Get("/menus/{menu_id}/products", app.listMenuProductsHandler)
+		r.Post("/menus/{menu_id}/products/{product_id}", app.createProductHandler)
+		r.Put("/menus/{menu_id}/products/{product_id}", app.updateProductHandler)
+		r.Delete("/menus/{menu_id}/products/{product_id}", app.deleteProductHandler)
+		r.Post("/menus/{menu_id}/products/{product_id}/attribute-groups/{group_id}", app.linkAttributeGroupHandler)
+		r.Delete("/menus/{menu_id}/products/{product_id}/attribute-groups/{group_id}", app.unlinkAttributeGroupHandler)
 
+		r.Post("/menus/{menu_id}/attribute-groups/{group_id}", app.createAttributeGroupHandler)
+		r.Put("/menus/{menu_id}/attribute-groups/{group_id}", app.updateAttributeGroupHandler)
+		r.Delete("/menus/{menu_id}/attribute-groups/{group_id}", app.deleteAttributeGroupHandler)
+
+		r.Post("/menus/{menu_id}/attributes/{attribute_id}", app.createAttributeHandler)
+		r.Put("/menus/{menu_id}/attributes/{attribute_id}", app.updateAttributeHandler)
+		r.Delete("/menus/{menu_id}/attributes/{attribute_id}", app.deleteAttributeHandler)
+
+		r.Get("/products", app.searchProductsHandler)
 		r.Patch("/products/{product_id}/status", app.updateProductStatusHandler)
+		r.Get("/products/{product_id}/audit", app.productAuditHandler)
+		r.Get("/audit/export", app.exportAuditHandler)
+		r.Get("/audit-log", app.listAuditLogHandler)
+		r.Get("/audit-log/{entry_id}", app.getAuditLogEntryHandler)
+		r.Patch("/products/status:batch", app.batchUpdateProductStatusHandler)
+
+		r.Get("/restaurants/{restaurant_id}/stop-list", app.stopListHandler)
+		r.Get("/restaurants/{restaurant_id}/audit", app.restaurantAuditHandler)
+		r.Patch("/restaurants/{restaurant_id}/products/{product_id}/status", app.updateRestaurantProductStatusHandler)
+		r.Patch("/restaurants/{restaurant_id}/attributes/{attribute_id}/status", app.updateAttributeStatusHandler)
+
+		r.Get("/restaurants/{restaurant_id}/branches", app.listBranchesHandler)
+		r.Post("/restaurants/{restaurant_id}/branches/{branch_id}", app.createBranchHandler)
+		r.Get("/restaurants/{restaurant_id}/branches/{branch_id}", app.getBranchHandler)
+		r.Delete("/restaurants/{restaurant_id}/branches/{branch_id}", app.deleteBranchHandler)
+		r.Get("/restaurants/{restaurant_id}/branches/{branch_id}/menu", app.getBranchMenuHandler)
+		r.Post("/restaurants/{restaurant_id}/branches/{branch_id}/quote", app.branchQuoteHandler)
+		r.Put("/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/price", app.setBranchProductPriceHandler)
+		r.Delete("/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/price", app.deleteBranchProductPriceHandler)
+		r.Patch("/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/status", app.updateBranchProductStatusHandler)
+		r.Put("/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/price", app.setBranchAttributePriceHandler)
+		r.Delete("/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/price", app.deleteBranchAttributePriceHandler)
+		r.Patch("/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/status", app.updateBranchAttributeStatusHandler)
+
+		r.Get("/restaurants/{restaurant_id}/webhooks", app.listWebhooksHandler)
+		r.Post("/restaurants/{restaurant_id}/webhooks", app.createWebhookHandler)
+		r.Get("/restaurants/{restaurant_id}/webhooks/{webhook_id}", app.getWebhookHandler)
+		r.Put("/restaurants/{restaurant_id}/webhooks/{webhook_id}", app.updateWebhookHandler)
+		r.Delete("/restaurants/{restaurant_id}/webhooks/{webhook_id}", app.deleteWebhookHandler)
+		r.Get("/restaurants/{restaurant_id}/webhooks/{webhook_id}/deliveries", app.listWebhookDeliveriesHandler)
 
 		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.addr)
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
@@ -103,6 +193,31 @@ func (app *application) run(mux http.Handler) error {
 			return fmt.Errorf("failed to start product worker: %w", err)
 		}
 	}
+	if app.purgeWorker != nil {
+		if err := app.purgeWorker.Start(); err != nil {
+			return fmt.Errorf("failed to start menu purge worker: %w", err)
+		}
+	}
+	if app.restoreWorker != nil {
+		if err := app.restoreWorker.Start(); err != nil {
+			return fmt.Errorf("failed to start restore scheduler: %w", err)
+		}
+	}
+	if app.archiveWorker != nil {
+		if err := app.archiveWorker.Start(); err != nil {
+			return fmt.Errorf("failed to start audit archive worker: %w", err)
+		}
+	}
+	if app.outboxRelay != nil {
+		if err := app.outboxRelay.Start(); err != nil {
+			return fmt.Errorf("failed to start outbox relay: %w", err)
+		}
+	}
+	if app.webhookWorker != nil {
+		if err := app.webhookWorker.Start(); err != nil {
+			return fmt.Errorf("failed to start webhook dispatcher: %w", err)
+		}
+	}
 
 	srv := &http.Server{
 		Addr:         app.config.addr,
@@ -131,6 +246,21 @@ func (app *application) run(mux http.Handler) error {
 		if app.productWorker != nil {
 			app.productWorker.Stop()
 		}
+		if app.purgeWorker != nil {
+			app.purgeWorker.Stop()
+		}
+		if app.restoreWorker != nil {
+			app.restoreWorker.Stop()
+		}
+		if app.archiveWorker != nil {
+			app.archiveWorker.Stop()
+		}
+		if app.outboxRelay != nil {
+			app.outboxRelay.Stop()
+		}
+		if app.webhookWorker != nil {
+			app.webhookWorker.Stop()
+		}
 
 		if app.storage != nil {
 			if err := app.storage.Close(ctx); err != nil {
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AttributeGroupRequest struct {
+	Name       string   `json:"name" validate:"required"`
+	Min        int      `json:"min" validate:"gte=0"`
+	Max        int      `json:"max" validate:"gte=0"`
+	Attributes []string `json:"attributes"`
+	UserID     string   `json:"user_id,omitempty"`
+}
+
+type AttributeRequest struct {
+	Name   string  `json:"name" validate:"required"`
+	Min    int     `json:"min" validate:"gte=0"`
+	Max    int     `json:"max" validate:"gte=0"`
+	Price  float64 `json:"price" validate:"gte=0"`
+	UserID string  `json:"user_id,omitempty"`
+}
+
+type UpdateAttributeStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=available not_available"`
+	Reason string `json:"reason"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+// createAttributeGroupHandler godoc
+//
+//	@Summary		Create attribute group
+//	@Description	Adds an attribute group to a menu
+//	@Tags			attributes
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id		path		string					true	"Menu ID"
+//	@Param			group_id	path		string					true	"Attribute group ID"
+//	@Param			request		body		AttributeGroupRequest	true	"Attribute group"
+//	@Param			If-Match	header		string					true	"Menu ETag"
+//	@Success		201			{object}	domain.AttributeGroup
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/attribute-groups/{group_id} [post]
+func (app *application) createAttributeGroupHandler(w http.ResponseWriter, r *http.Request) {
+	app.saveAttributeGroup(w, r, http.StatusCreated, app.menuService.CreateAttributeGroup)
+}
+
+// updateAttributeGroupHandler godoc
+//
+//	@Summary		Update attribute group
+//	@Description	Replaces an attribute group, including its min/max bounds and attributes
+//	@Tags			attributes
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id		path		string					true	"Menu ID"
+//	@Param			group_id	path		string					true	"Attribute group ID"
+//	@Param			request		body		AttributeGroupRequest	true	"Attribute group"
+//	@Param			If-Match	header		string					true	"Menu ETag"
+//	@Success		200			{object}	domain.AttributeGroup
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/attribute-groups/{group_id} [put]
+func (app *application) updateAttributeGroupHandler(w http.ResponseWriter, r *http.Request) {
+	app.saveAttributeGroup(w, r, http.StatusOK, app.menuService.UpdateAttributeGroup)
+}
+
+// deleteAttributeGroupHandler godoc
+//
+//	@Summary		Delete attribute group
+//	@Description	Removes an attribute group that is not attached to any product
+//	@Tags			attributes
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			group_id	path		string	true	"Attribute group ID"
+//	@Param			If-Match	header		string	true	"Menu ETag"
+//	@Param			user_id		query		string	false	"User ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/attribute-groups/{group_id} [delete]
+func (app *application) deleteAttributeGroupHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.menuService.DeleteAttributeGroup(r.Context(), menuID, version, chi.URLParam(r, "group_id"), userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// linkAttributeGroupHandler godoc
+//
+//	@Summary		Link attribute group to product
+//	@Description	Attaches an attribute group to a product
+//	@Tags			attributes
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			product_id	path		string	true	"Product ID"
+//	@Param			group_id	path		string	true	"Attribute group ID"
+//	@Param			If-Match	header		string	true	"Menu ETag"
+//	@Param			user_id		query		string	false	"User ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products/{product_id}/attribute-groups/{group_id} [post]
+func (app *application) linkAttributeGroupHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	err = app.menuService.LinkAttributeGroup(r.Context(), menuID, version, chi.URLParam(r, "product_id"), chi.URLParam(r, "group_id"), userID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// unlinkAttributeGroupHandler godoc
+//
+//	@Summary		Unlink attribute group from product
+//	@Description	Detaches an attribute group from a product
+//	@Tags			attributes
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			product_id	path		string	true	"Product ID"
+//	@Param			group_id	path		string	true	"Attribute group ID"
+//	@Param			If-Match	header		string	true	"Menu ETag"
+//	@Param			user_id		query		string	false	"User ID"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products/{product_id}/attribute-groups/{group_id} [delete]
+func (app *application) unlinkAttributeGroupHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	err = app.menuService.UnlinkAttributeGroup(r.Context(), menuID, version, chi.URLParam(r, "product_id"), chi.URLParam(r, "group_id"), userID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createAttributeHandler godoc
+//
+//	@Summary		Create attribute
+//	@Description	Adds an attribute (modifier) to a menu
+//	@Tags			attributes
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id			path		string				true	"Menu ID"
+//	@Param			attribute_id	path		string				true	"Attribute ID"
+//	@Param			request			body		AttributeRequest	true	"Attribute"
+//	@Param			If-Match		header		string				true	"Menu ETag"
+//	@Success		201				{object}	domain.Attribute
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		428				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/menus/{menu_id}/attributes/{attribute_id} [post]
+func (app *application) createAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	app.saveAttribute(w, r, http.StatusCreated, app.menuService.CreateAttribute)
+}
+
+// updateAttributeHandler godoc
+//
+//	@Summary		Update attribute
+//	@Description	Replaces an attribute (modifier) in a menu
+//	@Tags			attributes
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id			path		string				true	"Menu ID"
+//	@Param			attribute_id	path		string				true	"Attribute ID"
+//	@Param			request			body		AttributeRequest	true	"Attribute"
+//	@Param			If-Match		header		string				true	"Menu ETag"
+//	@Success		200				{object}	domain.Attribute
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		428				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/menus/{menu_id}/attributes/{attribute_id} [put]
+func (app *application) updateAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	app.saveAttribute(w, r, http.StatusOK, app.menuService.UpdateAttribute)
+}
+
+// deleteAttributeHandler godoc
+//
+//	@Summary		Delete attribute
+//	@Description	Removes an attribute that is not used by any attribute group
+//	@Tags			attributes
+//	@Produce		json
+//	@Param			menu_id			path		string	true	"Menu ID"
+//	@Param			attribute_id	path		string	true	"Attribute ID"
+//	@Param			If-Match		header		string	true	"Menu ETag"
+//	@Param			user_id			query		string	false	"User ID"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		428				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/menus/{menu_id}/attributes/{attribute_id} [delete]
+func (app *application) deleteAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.menuService.DeleteAttribute(r.Context(), menuID, version, chi.URLParam(r, "attribute_id"), userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateAttributeStatusHandler godoc
+//
+//	@Summary		Update attribute status
+//	@Description	Update the status of an attribute (modifier) in the current menu of a restaurant and all its branches. Unavailable attributes can't be selected in quotes
+//	@Tags			attributes
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string							true	"Restaurant ID"
+//	@Param			attribute_id	path		string							true	"Attribute ID"
+//	@Param			request			body		UpdateAttributeStatusRequest	true	"Status update request"
+//	@Success		202				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/attributes/{attribute_id}/status [patch]
+func (app *application) updateAttributeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateAttributeStatus(w, r, "")
+}
+
+func (app *application) updateAttributeStatus(w http.ResponseWriter, r *http.Request, branchID string) {
+	restaurantID := chi.URLParam(r, "restaurant_id")
+	attributeID := chi.URLParam(r, "attribute_id")
+	if restaurantID == "" || attributeID == "" {
+		app.badRequestResponse(w, r, errors.New("restaurant_id and attribute_id are required"))
+		return
+	}
+
+	var req UpdateAttributeStatusRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.productService.UpdateAttributeStatus(r.Context(), restaurantID, branchID, attributeID, req.Status, req.Reason, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Status update queued",
+	}
+
+	if err := app.jsonRespone(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) saveAttributeGroup(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	save func(ctx context.Context, menuID primitive.ObjectID, version int64, group domain.AttributeGroup, userID string) error,
+) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req AttributeGroupRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	group := domain.AttributeGroup{
+		ID:         chi.URLParam(r, "group_id"),
+		Name:       req.Name,
+		Min:        req.Min,
+		Max:        req.Max,
+		Attributes: req.Attributes,
+	}
+	if group.Attributes == nil {
+		group.Attributes = []string{}
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := save(r.Context(), menuID, version, group, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, status, group); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) saveAttribute(
+	w http.ResponseWriter,
+	r *http.Request,
+	status int,
+	save func(ctx context.Context, menuID primitive.ObjectID, version int64, attribute *domain.Attribute, userID string) error,
+) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	var req AttributeRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	attribute := domain.Attribute{
+		ID:    chi.URLParam(r, "attribute_id"),
+		Name:  req.Name,
+		Min:   req.Min,
+		Max:   req.Max,
+		Price: req.Price,
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := save(r.Context(), menuID, version, &attribute, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", menuETag(version+1))
+	if err := app.jsonRespone(w, status, attribute); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/go-chi/chi"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+
+	// exportWriteTimeout replaces the server write timeout for exports
+	exportWriteTimeout = 10 * time.Minute
+)
+
+// productAuditHandler godoc
+//
+//	@Summary		Get product status history
+//	@Description	Lists status changes of a product newest first. Pass next_cursor of a page as cursor to get the next one
+//	@Tags			products
+//	@Produce		json
+//	@Param			product_id		path		string	true	"Product ID"
+//	@Param			restaurant_id	query		string	false	"Restaurant ID"
+//	@Param			event_type		query		string	false	"Event type, e.g. product.status_changed"
+//	@Param			user_id			query		string	false	"User who made the change"
+//	@Param			reason			query		string	false	"Reason of the change"
+//	@Param			from			query		string	false	"Start of the time range (RFC 3339), inclusive"
+//	@Param			to				query		string	false	"End of the time range (RFC 3339), exclusive"
+//	@Param			limit			query		int		false	"Page size"
+//	@Param			cursor			query		string	false	"Cursor of the next page"
+//	@Success		200				{object}	domain.ProductStatusAuditPage
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/products/{product_id}/audit [get]
+func (app *application) productAuditHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	filter.ProductID = chi.URLParam(r, "product_id")
+
+	page, err := app.productService.GetProductAudit(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// restaurantAuditHandler godoc
+//
+//	@Summary		Get restaurant status feed
+//	@Description	Lists status changes of all products and attributes of a restaurant newest first. Pass next_cursor of a page as cursor to get the next one
+//	@Tags			products
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			event_type		query		string	false	"Event type, e.g. product.status_changed"
+//	@Param			user_id			query		string	false	"User who made the change"
+//	@Param			reason			query		string	false	"Reason of the change"
+//	@Param			from			query		string	false	"Start of the time range (RFC 3339), inclusive"
+//	@Param			to				query		string	false	"End of the time range (RFC 3339), exclusive"
+//	@Param			limit			query		int		false	"Page size"
+//	@Param			cursor			query		string	false	"Cursor of the next page"
+//	@Success		200				{object}	domain.ProductStatusAuditPage
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/audit [get]
+func (app *application) restaurantAuditHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	filter.RestaurantID = chi.URLParam(r, "restaurant_id")
+
+	page, err := app.productService.GetRestaurantAudit(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func parseAuditFilter(query url.Values) (domain.ProductStatusAuditFilter, error) {
+	filter := domain.ProductStatusAuditFilter{
+		RestaurantID: query.Get("restaurant_id"),
+		EventType:    query.Get("event_type"),
+		UserID:       query.Get("user_id"),
+		Reason:       query.Get("reason"),
+		Limit:        defaultAuditLimit,
+	}
+
+	if v := query.Get("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from: %q", v)
+		}
+		filter.From = &from
+	}
+
+	if v := query.Get("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to: %q", v)
+		}
+		filter.To = &to
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxAuditLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// exportAuditHandler godoc
+//
+//	@Summary		Export status audit
+//	@Description	Streams status audit records of a time range oldest first as gzip-compressed CSV or NDJSON
+//	@Tags			products
+//	@Produce		application/gzip
+//	@Param			from			query		string	true	"Start of the time range (RFC 3339), inclusive"
+//	@Param			to				query		string	true	"End of the time range (RFC 3339), exclusive"
+//	@Param			restaurant_id	query		string	false	"Restaurant ID"
+//	@Param			format			query		string	false	"Output format"	Enums(csv, ndjson)
+//	@Success		200				{file}		file
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/audit/export [get]
+func (app *application) exportAuditHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	filter.RestaurantID = r.URL.Query().Get("restaurant_id")
+
+	if filter.From == nil || filter.To == nil {
+		app.badRequestResponse(w, r, errors.New("from and to are required"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "ndjson" {
+		app.badRequestResponse(w, r, fmt.Errorf("unsupported format %q", format))
+		return
+	}
+
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil {
+		app.logger.Warnw("failed to extend export write deadline", "error", err)
+	}
+
+	filename := fmt.Sprintf("audit-%s-%s.%s.gz", filter.From.UTC().Format("20060102"), filter.To.UTC().Format("20060102"), format)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	gz := gzip.NewWriter(w)
+
+	if format == "csv" {
+		err = app.exportAuditCSV(r.Context(), gz, filter)
+	} else {
+		encoder := json.NewEncoder(gz)
+		err = app.productService.ExportAudit(r.Context(), filter, func(audit domain.ProductStatusAudit) error {
+			return encoder.Encode(audit)
+		})
+	}
+	if err != nil {
+		// the status is already sent, leave the archive truncated so the
+		// client can't mistake it for a complete one
+		app.logger.Errorw("failed to export audit", "restaurant_id", filter.RestaurantID, "error", err)
+		return
+	}
+
+	if err := gz.Close(); err != nil {
+		app.logger.Errorw("failed to finish audit export", "restaurant_id", filter.RestaurantID, "error", err)
+	}
+}
+
+func (app *application) exportAuditCSV(ctx context.Context, w io.Writer, filter domain.ProductStatusAuditFilter) error {
+	writer := csv.NewWriter(w)
+	header := []string{"timestamp", "restaurant_id", "menu_id", "branch_id", "product_id", "attribute_id", "event_type", "old_status", "new_status", "reason", "user_id", "rejected"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	err := app.productService.ExportAudit(ctx, filter, func(audit domain.ProductStatusAudit) error {
+		var menuID string
+		if !audit.MenuID.IsZero() {
+			menuID = audit.MenuID.Hex()
+		}
+
+		return writer.Write([]string{
+			audit.Timestamp.UTC().Format(time.RFC3339),
+			audit.RestaurantID,
+			menuID,
+			audit.BranchID,
+			audit.ProductID,
+			audit.AttributeID,
+			audit.EventType,
+			audit.OldStatus,
+			audit.NewStatus,
+			audit.Reason,
+			audit.UserID,
+			strconv.FormatBool(audit.Rejected),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
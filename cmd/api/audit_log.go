@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+// listAuditLogHandler godoc
+//
+//	@Summary		List audit log
+//	@Description	Lists changes of menus, products, attributes, branches, parsing tasks and webhooks newest first, with snapshots of the changed fields before and after each change. Pass next_cursor of a page as cursor to get the next one
+//	@Tags			audit
+//	@Produce		json
+//	@Param			entity_type		query		string	false	"Entity type: menu, product, attribute, attribute_group, branch, parsing_task or webhook"
+//	@Param			entity_id		query		string	false	"Entity ID"
+//	@Param			restaurant_id	query		string	false	"Restaurant ID"
+//	@Param			actor			query		string	false	"User who made the change"
+//	@Param			action			query		string	false	"Action, e.g. menu.deleted"
+//	@Param			from			query		string	false	"Start of the time range (RFC 3339), inclusive"
+//	@Param			to				query		string	false	"End of the time range (RFC 3339), exclusive"
+//	@Param			limit			query		int		false	"Page size"
+//	@Param			cursor			query		string	false	"Cursor of the next page"
+//	@Success		200				{object}	domain.AuditLogPage
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/audit-log [get]
+func (app *application) listAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// time range and limit are parsed the same way as for status audits
+	auditFilter, err := parseAuditFilter(query)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter := domain.AuditLogFilter{
+		EntityType:   query.Get("entity_type"),
+		EntityID:     query.Get("entity_id"),
+		RestaurantID: query.Get("restaurant_id"),
+		Actor:        query.Get("actor"),
+		Action:       query.Get("action"),
+		From:         auditFilter.From,
+		To:           auditFilter.To,
+		Limit:        auditFilter.Limit,
+	}
+
+	page, err := app.auditLogService.ListEntries(r.Context(), filter, query.Get("cursor"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getAuditLogEntryHandler godoc
+//
+//	@Summary		Get audit log entry
+//	@Description	Get a single change from the audit log
+//	@Tags			audit
+//	@Produce		json
+//	@Param			entry_id	path		string	true	"Entry ID"
+//	@Success		200			{object}	domain.AuditLogEntry
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/audit-log/{entry_id} [get]
+func (app *application) getAuditLogEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entryID, err := objectIDParam(r, "entry_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry, err := app.auditLogService.GetEntry(r.Context(), entryID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, entry); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/go-chi/chi"
+)
+
+type BranchRequest struct {
+	Name   string `json:"name" validate:"required"`
+	UserID string `json:"user_id,omitempty"`
+}
+
+type PriceOverrideRequest struct {
+	Price  *float64 `json:"price" validate:"required,gte=0"`
+	UserID string   `json:"user_id,omitempty"`
+}
+
+// listBranchesHandler godoc
+//
+//	@Summary		List branches
+//	@Description	Lists the branches of a restaurant with their overrides
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Success		200				{object}	[]domain.Branch
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches [get]
+func (app *application) listBranchesHandler(w http.ResponseWriter, r *http.Request) {
+	branches, err := app.branchService.ListBranches(r.Context(), chi.URLParam(r, "restaurant_id"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, branches); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createBranchHandler godoc
+//
+//	@Summary		Create branch
+//	@Description	Adds a branch to a restaurant. Branches share the restaurant's current menu
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string			true	"Restaurant ID"
+//	@Param			branch_id		path		string			true	"Branch ID"
+//	@Param			request			body		BranchRequest	true	"Branch"
+//	@Success		201				{object}	domain.Branch
+//	@Failure		400				{object}	map[string]string
+//	@Failure		409				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id} [post]
+func (app *application) createBranchHandler(w http.ResponseWriter, r *http.Request) {
+	var req BranchRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	branch := &domain.Branch{
+		ID:           chi.URLParam(r, "branch_id"),
+		RestaurantID: chi.URLParam(r, "restaurant_id"),
+		Name:         req.Name,
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.branchService.CreateBranch(r.Context(), branch, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusCreated, branch); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getBranchHandler godoc
+//
+//	@Summary		Get branch
+//	@Description	Get a branch with its price and status overrides
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			branch_id		path		string	true	"Branch ID"
+//	@Success		200				{object}	domain.Branch
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id} [get]
+func (app *application) getBranchHandler(w http.ResponseWriter, r *http.Request) {
+	branch, err := app.branchService.GetBranch(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, branch); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteBranchHandler godoc
+//
+//	@Summary		Delete branch
+//	@Description	Removes a branch and its overrides
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			branch_id		path		string	true	"Branch ID"
+//	@Param			user_id			query		string	false	"User ID"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id} [delete]
+func (app *application) deleteBranchHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.branchService.DeleteBranch(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"), userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getBranchMenuHandler godoc
+//
+//	@Summary		Get branch menu
+//	@Description	Returns the restaurant's current menu with the branch's prices and statuses applied
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			branch_id		path		string	true	"Branch ID"
+//	@Success		200				{object}	domain.Menu
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/menu [get]
+func (app *application) getBranchMenuHandler(w http.ResponseWriter, r *http.Request) {
+	menu, err := app.branchService.ResolvedMenu(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, menu); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// branchQuoteHandler godoc
+//
+//	@Summary		Quote branch order line
+//	@Description	Like the menu quote, with the branch's prices and statuses applied
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string			true	"Restaurant ID"
+//	@Param			branch_id		path		string			true	"Branch ID"
+//	@Param			request			body		QuoteRequest	true	"Order line"
+//	@Success		200				{object}	domain.Quote
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/quote [post]
+func (app *application) branchQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	var req QuoteRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	quote, err := app.branchService.Quote(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"), req.toDomain())
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, quote); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// setBranchProductPriceHandler godoc
+//
+//	@Summary		Override branch product price
+//	@Description	Sets the price of a product in the branch
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string					true	"Restaurant ID"
+//	@Param			branch_id		path		string					true	"Branch ID"
+//	@Param			product_id		path		string					true	"Product ID"
+//	@Param			request			body		PriceOverrideRequest	true	"Price"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/price [put]
+func (app *application) setBranchProductPriceHandler(w http.ResponseWriter, r *http.Request) {
+	app.setBranchPrice(w, r, "product_id", app.branchService.SetProductPrice)
+}
+
+// deleteBranchProductPriceHandler godoc
+//
+//	@Summary		Remove branch product price
+//	@Description	Removes the price override of a product, the branch uses the menu price again
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			branch_id		path		string	true	"Branch ID"
+//	@Param			product_id		path		string	true	"Product ID"
+//	@Param			user_id			query		string	false	"User ID"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/price [delete]
+func (app *application) deleteBranchProductPriceHandler(w http.ResponseWriter, r *http.Request) {
+	app.deleteBranchPrice(w, r, "product_id", app.branchService.SetProductPrice)
+}
+
+// updateBranchProductStatusHandler godoc
+//
+//	@Summary		Update branch product status
+//	@Description	Overrides the status of a product in one branch. Status changes for the whole restaurant drop branch overrides
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string						true	"Restaurant ID"
+//	@Param			branch_id		path		string						true	"Branch ID"
+//	@Param			product_id		path		string						true	"Product ID"
+//	@Param			request			body		UpdateProductStatusRequest	true	"Status update request"
+//	@Success		202				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/products/{product_id}/status [patch]
+func (app *application) updateBranchProductStatusHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID := chi.URLParam(r, "restaurant_id")
+	branchID := chi.URLParam(r, "branch_id")
+	if restaurantID == "" || branchID == "" {
+		app.badRequestResponse(w, r, errors.New("restaurant_id and branch_id are required"))
+		return
+	}
+
+	app.updateProductStatus(w, r, restaurantID, branchID)
+}
+
+// setBranchAttributePriceHandler godoc
+//
+//	@Summary		Override branch attribute price
+//	@Description	Sets the price of an attribute (modifier) in the branch
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string					true	"Restaurant ID"
+//	@Param			branch_id		path		string					true	"Branch ID"
+//	@Param			attribute_id	path		string					true	"Attribute ID"
+//	@Param			request			body		PriceOverrideRequest	true	"Price"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/price [put]
+func (app *application) setBranchAttributePriceHandler(w http.ResponseWriter, r *http.Request) {
+	app.setBranchPrice(w, r, "attribute_id", app.branchService.SetAttributePrice)
+}
+
+// deleteBranchAttributePriceHandler godoc
+//
+//	@Summary		Remove branch attribute price
+//	@Description	Removes the price override of an attribute, the branch uses the menu price again
+//	@Tags			branches
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			branch_id		path		string	true	"Branch ID"
+//	@Param			attribute_id	path		string	true	"Attribute ID"
+//	@Param			user_id			query		string	false	"User ID"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/price [delete]
+func (app *application) deleteBranchAttributePriceHandler(w http.ResponseWriter, r *http.Request) {
+	app.deleteBranchPrice(w, r, "attribute_id", app.branchService.SetAttributePrice)
+}
+
+// updateBranchAttributeStatusHandler godoc
+//
+//	@Summary		Update branch attribute status
+//	@Description	Overrides the status of an attribute (modifier) in one branch
+//	@Tags			branches
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string							true	"Restaurant ID"
+//	@Param			branch_id		path		string							true	"Branch ID"
+//	@Param			attribute_id	path		string							true	"Attribute ID"
+//	@Param			request			body		UpdateAttributeStatusRequest	true	"Status update request"
+//	@Success		202				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/branches/{branch_id}/attributes/{attribute_id}/status [patch]
+func (app *application) updateBranchAttributeStatusHandler(w http.ResponseWriter, r *http.Request) {
+	branchID := chi.URLParam(r, "branch_id")
+	if branchID == "" {
+		app.badRequestResponse(w, r, errors.New("branch_id is required"))
+		return
+	}
+
+	app.updateAttributeStatus(w, r, branchID)
+}
+
+func (app *application) setBranchPrice(
+	w http.ResponseWriter,
+	r *http.Request,
+	param string,
+	set func(ctx context.Context, restaurantID, branchID, id string, price *float64, userID string) error,
+) {
+	var req PriceOverrideRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := set(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"), chi.URLParam(r, param), req.Price, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteBranchPrice(
+	w http.ResponseWriter,
+	r *http.Request,
+	param string,
+	set func(ctx context.Context, restaurantID, branchID, id string, price *float64, userID string) error,
+) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := set(r.Context(), chi.URLParam(r, "restaurant_id"), chi.URLParam(r, "branch_id"), chi.URLParam(r, param), nil, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
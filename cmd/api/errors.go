@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
 )
 
 func (app *application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
@@ -28,6 +31,12 @@ func (app *application) conflictResponse(w http.ResponseWriter, r *http.Request,
 	writeJSONError(w, http.StatusConflict, err.Error())
 }
 
+func (app *application) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.logger.Warnw("precondition required", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+
+	writeJSONError(w, http.StatusPreconditionRequired, err.Error())
+}
+
 func (app *application) notFoundError(w http.ResponseWriter, r *http.Request, err error) {
 	app.logger.Warnf("not found error", "method", r.Method, "path", r.URL.Path, "error", err.Error())
 
@@ -55,3 +64,37 @@ func (app *application) rateLimitExceededResponse(w http.ResponseWriter, r *http
 
 	writeJSONError(w, http.StatusTooManyRequests, "rate limit exceeded, retry after: "+retryAfter)
 }
+
+// domainErrorResponse maps errors returned by services to HTTP responses.
+func (app *application) domainErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrMenuNotFound),
+		errors.Is(err, domain.ErrProductNotFound),
+		errors.Is(err, domain.ErrAttributeGroupNotFound),
+		errors.Is(err, domain.ErrAttributeNotFound),
+		errors.Is(err, domain.ErrBranchNotFound),
+		errors.Is(err, domain.ErrAuditLogEntryNotFound),
+		errors.Is(err, domain.ErrWebhookNotFound):
+		app.notFoundError(w, r, err)
+	case errors.Is(err, domain.ErrVersionConflict),
+		errors.Is(err, domain.ErrProductExists),
+		errors.Is(err, domain.ErrProductAmbiguous),
+		errors.Is(err, domain.ErrAttributeGroupExists),
+		errors.Is(err, domain.ErrAttributeGroupInUse),
+		errors.Is(err, domain.ErrAttributeExists),
+		errors.Is(err, domain.ErrAttributeInUse),
+		errors.Is(err, domain.ErrBranchExists):
+		app.conflictResponse(w, r, err)
+	case errors.Is(err, domain.ErrInvalidFields),
+		errors.Is(err, domain.ErrInvalidProduct),
+		errors.Is(err, domain.ErrProductUnavailable),
+		errors.Is(err, domain.ErrInvalidQuote),
+		errors.Is(err, domain.ErrInvalidAttributeGroup),
+		errors.Is(err, domain.ErrInvalidAttribute),
+		errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrInvalidWebhook):
+		app.badRequestResponse(w, r, err)
+	default:
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.uber.org/zap"
+)
+
+func TestDomainErrorResponse(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: domain.ErrInvalidCursor, want: http.StatusBadRequest},
+		{err: fmt.Errorf("failed to list audit: %w", domain.ErrInvalidCursor), want: http.StatusBadRequest},
+		{err: domain.ErrProductNotFound, want: http.StatusNotFound},
+		{err: domain.ErrVersionConflict, want: http.StatusConflict},
+		{err: fmt.Errorf("unexpected"), want: http.StatusInternalServerError},
+	}
+
+	app := &application{logger: zap.NewNop().Sugar()}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/v1/audit-log?cursor=x", nil)
+
+			app.domainErrorResponse(w, r, tt.err)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -58,6 +58,27 @@ func main() {
 			RetryDelay:    time.Second * 2,
 			PrefetchCount: env.GetInt("RABBITMQ_PREFETCH_COUNT", 10),
 		},
+		menuPurge: menuPurgeConfig{
+			Retention: time.Hour * time.Duration(env.GetInt("MENU_RETENTION_HOURS", 720)),
+			Interval:  time.Minute * time.Duration(env.GetInt("MENU_PURGE_INTERVAL_MINUTES", 60)),
+		},
+		restores: restoresConfig{
+			Interval: time.Second * time.Duration(env.GetInt("RESTORE_SCHEDULER_INTERVAL_SECONDS", 30)),
+		},
+		auditArchive: auditArchiveConfig{
+			Retention: time.Hour * 24 * time.Duration(env.GetInt("AUDIT_RETENTION_DAYS", 365)),
+			Interval:  time.Minute * time.Duration(env.GetInt("AUDIT_ARCHIVE_INTERVAL_MINUTES", 1440)),
+			Dir:       env.GetString("AUDIT_ARCHIVE_DIR", "./archive/audit"),
+		},
+		outbox: outboxConfig{
+			Interval: time.Millisecond * time.Duration(env.GetInt("OUTBOX_RELAY_INTERVAL_MS", 500)),
+		},
+		webhooks: webhooksConfig{
+			Interval:    time.Millisecond * time.Duration(env.GetInt("WEBHOOK_DISPATCH_INTERVAL_MS", 1000)),
+			Timeout:     time.Second * time.Duration(env.GetInt("WEBHOOK_TIMEOUT_SECONDS", 10)),
+			MaxAttempts: env.GetInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			Concurrency: env.GetInt("WEBHOOK_CONCURRENCY", 10),
+		},
 		googleCreds: env.GetString("GOOGLE_CREDENTIALS_PATH", ""),
 	}
 
@@ -96,6 +117,14 @@ func main() {
 	menuRepo := mongo.NewMenuRepository(storage.Database())
 	parsingTaskRepo := mongo.NewParsingTaskRepository(storage.Database())
 	productStatusAuditRepo := mongo.NewProductStatusAuditRepository(storage.Database())
+	auditLogRepo := mongo.NewAuditLogRepository(storage.Database())
+	scheduledRestoreRepo := mongo.NewScheduledRestoreRepository(storage.Database())
+	branchRepo := mongo.NewBranchRepository(storage.Database())
+	outboxRepo := mongo.NewOutboxRepository(storage.Database())
+	processedEventRepo := mongo.NewProcessedEventRepository(storage.Database())
+	statusSequenceRepo := mongo.NewStatusSequenceRepository(storage.Database())
+	webhookRepo := mongo.NewWebhookRepository(storage.Database())
+	webhookDeliveryRepo := mongo.NewWebhookDeliveryRepository(storage.Database())
 
 	// rabbitmq broker
 	broker, err := queue.NewRabbitMQBroker(queue.Config{
@@ -131,8 +160,11 @@ func main() {
 	parsingService := service.NewParsingService(
 		parsingTaskRepo,
 		menuRepo,
+		auditLogRepo,
+		outboxRepo,
+		webhookRepo,
+		webhookDeliveryRepo,
 		googleParser,
-		broker,
 		storage,
 		logger,
 	)
@@ -140,25 +172,72 @@ func main() {
 	productService := service.NewProductService(
 		menuRepo,
 		productStatusAuditRepo,
-		broker,
+		auditLogRepo,
+		scheduledRestoreRepo,
+		branchRepo,
+		outboxRepo,
+		processedEventRepo,
+		statusSequenceRepo,
+		webhookRepo,
+		webhookDeliveryRepo,
 		storage,
 		logger,
 	)
 
+	menuService := service.NewMenuService(menuRepo, auditLogRepo, storage, logger)
+	branchService := service.NewBranchService(branchRepo, menuRepo, auditLogRepo, storage, logger)
+	auditLogService := service.NewAuditLogService(auditLogRepo, logger)
+	outboxService := service.NewOutboxService(outboxRepo, broker, logger)
+	auditArchiveService := service.NewAuditArchiveService(productStatusAuditRepo, cfg.auditArchive.Dir, logger)
+	webhookService := service.NewWebhookService(
+		webhookRepo,
+		webhookDeliveryRepo,
+		auditLogRepo,
+		storage,
+		cfg.webhooks.Timeout,
+		cfg.webhooks.MaxAttempts,
+		cfg.webhooks.Concurrency,
+		logger,
+	)
+
 	menuWorker := worker.NewMenuParsingWorker(parsingService, broker, logger)
 	productWorker := worker.NewProductStatusWorker(productService, broker, logger)
+	purgeWorker := worker.NewMenuPurgeWorker(
+		menuService,
+		cfg.menuPurge.Retention,
+		cfg.menuPurge.Interval,
+		logger,
+	)
+	restoreWorker := worker.NewRestoreScheduler(productService, cfg.restores.Interval, logger)
+	archiveWorker := worker.NewAuditArchiveWorker(
+		auditArchiveService,
+		cfg.auditArchive.Retention,
+		cfg.auditArchive.Interval,
+		logger,
+	)
+	outboxRelay := worker.NewOutboxRelay(outboxService, cfg.outbox.Interval, logger)
+	webhookWorker := worker.NewWebhookDispatcher(webhookService, cfg.webhooks.Interval, logger)
 
 	app := &application{
-		config:         cfg,
-		logger:         logger,
-		rateLimiter:    rateLimiter,
-		storage:        storage,
-		broker:         broker,
-		menuRepo:       menuRepo,
-		parsingService: parsingService,
-		productService: productService,
-		menuWorker:     menuWorker,
-		productWorker:  productWorker,
+		config:          cfg,
+		logger:          logger,
+		rateLimiter:     rateLimiter,
+		storage:         storage,
+		broker:          broker,
+		menuRepo:        menuRepo,
+		parsingService:  parsingService,
+		productService:  productService,
+		menuService:     menuService,
+		branchService:   branchService,
+		auditLogService: auditLogService,
+		webhookService:  webhookService,
+		menuWorker:      menuWorker,
+		productWorker:   productWorker,
+		purgeWorker:     purgeWorker,
+		restoreWorker:   restoreWorker,
+		archiveWorker:   archiveWorker,
+		outboxRelay:     outboxRelay,
+		webhookWorker:   webhookWorker,
 	}
 
 	mux := app.mount()
@@ -1,7 +1,15 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,11 +21,14 @@ import (
 //	@Description	Get menu details by menu ID
 //	@Tags			menus
 //	@Produce		json
-//	@Param			menu_id	path		string	true	"Menu ID"
-//	@Success		200		{object}	domain.Menu
-//	@Failure		400		{object}	map[string]string
-//	@Failure		404		{object}	map[string]string
-//	@Failure		500		{object}	map[string]string
+//	@Param			menu_id			path		string	true	"Menu ID"
+//	@Param			fields			query		string	false	"Comma-separated fields to return, e.g. products.id,products.name,products.status"
+//	@Param			If-None-Match	header		string	false	"ETag of a cached menu"
+//	@Success		200				{object}	domain.Menu
+//	@Success		304
+//	@Failure		400	{object}	map[string]string
+//	@Failure		404	{object}	map[string]string
+//	@Failure		500	{object}	map[string]string
 //	@Router			/menu/{menu_id} [get]
 func (app *application) getMenuHandler(w http.ResponseWriter, r *http.Request) {
 	menuIDStr := chi.URLParam(r, "menu_id")
@@ -32,13 +43,187 @@ func (app *application) getMenuHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	menu, err := app.menuRepo.GetByID(r.Context(), menuID)
+	var (
+		menu any
+		etag string
+	)
+	if fields := parseFields(r.URL.Query()); len(fields) > 0 {
+		doc, version, err := app.menuService.GetMenuFields(r.Context(), menuID, fields)
+		if err != nil {
+			app.domainErrorResponse(w, r, err)
+			return
+		}
+		menu, etag = doc, projectedMenuETag(version, fields)
+	} else {
+		full, err := app.menuRepo.GetByID(r.Context(), menuID)
+		if err != nil {
+			app.notFoundError(w, r, err)
+			return
+		}
+		menu, etag = full, menuETag(full.Version)
+	}
+
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, menu); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteMenuHandler godoc
+//
+//	@Summary		Delete menu
+//	@Description	Soft-deletes a menu. It is hidden from reads and purged after the retention period unless restored
+//	@Tags			menus
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			user_id		query		string	false	"User ID"
+//	@Param			If-Match	header		string	true	"Menu ETag"
+//	@Success		200			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id} [delete]
+func (app *application) deleteMenuHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.menuService.DeleteMenu(r.Context(), menuID, version, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// restoreMenuHandler godoc
+//
+//	@Summary		Restore menu
+//	@Description	Restores a soft-deleted menu that has not been purged yet
+//	@Tags			menus
+//	@Produce		json
+//	@Param			menu_id	path		string	true	"Menu ID"
+//	@Param			user_id	query		string	false	"User ID"
+//	@Success		200		{object}	domain.Menu
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/menus/{menu_id}/restore [post]
+func (app *application) restoreMenuHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	menu, err := app.menuService.RestoreMenu(r.Context(), menuID, userID)
 	if err != nil {
-		app.notFoundError(w, r, err)
+		app.domainErrorResponse(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", menuETag(menu.Version))
 	if err := app.jsonRespone(w, http.StatusOK, menu); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
+
+func objectIDParam(r *http.Request, name string) (primitive.ObjectID, error) {
+	id, err := primitive.ObjectIDFromHex(chi.URLParam(r, name))
+	if err != nil {
+		return primitive.NilObjectID, ErrInvalidID
+	}
+
+	return id, nil
+}
+
+var (
+	ErrIfMatchRequired = errors.New("If-Match header with the menu ETag is required")
+	ErrInvalidIfMatch  = errors.New("invalid If-Match header")
+)
+
+func menuETag(version int64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// projectedMenuETag is the ETag of a menu read limited to fields. The sorted
+// field list is part of the tag, so a cached projection never validates a
+// read of other fields. It is accepted in If-Match like the full menu's.
+func projectedMenuETag(version int64, fields []string) string {
+	canonical := slices.Clone(fields)
+	slices.Sort(canonical)
+	canonical = slices.Compact(canonical)
+
+	sum := sha256.Sum256([]byte(strings.Join(canonical, ",")))
+	return fmt.Sprintf(`"%d-%s"`, version, hex.EncodeToString(sum[:8]))
+}
+
+// etagMatches reports whether any entity tag in an If-None-Match style header
+// matches etag, using weak comparison.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// readIfMatch returns the menu version a mutating request was made against.
+// It writes an error response and returns false when the header is missing
+// or malformed.
+func (app *application) readIfMatch(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		app.preconditionRequiredResponse(w, r, ErrIfMatchRequired)
+		return 0, false
+	}
+
+	// projected reads tag the version with their fields
+	tag, _, _ := strings.Cut(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), "-")
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 0 {
+		app.badRequestResponse(w, r, ErrInvalidIfMatch)
+		return 0, false
+	}
+
+	return version, true
+}
+
+// parseFields reads the comma-separated "fields" query parameter.
+func parseFields(query url.Values) []string {
+	var fields []string
+	for _, field := range strings.Split(query.Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -10,6 +10,8 @@ import (
 type CreateParseTaskRequest struct {
 	SpreadsheetID  string `json:"spreadsheet_id" validate:"required"`
 	RestaurantName string `json:"restaurant_name" validate:"required"`
+	ResetStatuses  bool   `json:"reset_statuses"`
+	UserID         string `json:"user_id,omitempty"`
 }
 
 // createParseTaskHandler godoc
@@ -36,7 +38,13 @@ func (app *application) createParseTaskHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	taskID, err := app.parsingService.CreateParsingTask(r.Context(), req.SpreadsheetID, req.RestaurantName)
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	taskID, err := app.parsingService.CreateParsingTask(r.Context(), req.SpreadsheetID, req.RestaurantName, req.ResetStatuses, userID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
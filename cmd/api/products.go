@@ -3,24 +3,58 @@ package main
 import (
 	"errors"
 	"net/http"
+	"time"
 
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/service"
 	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 var (
 	ErrInvalidID = errors.New("invalid ID format")
 )
 
+type BatchProductStatusRequest struct {
+	Status     string           `json:"status" validate:"required,oneof=available not_available deleted"`
+	Reason     string           `json:"reason"`
+	UserID     string           `json:"user_id,omitempty"`
+	ProductIDs []string         `json:"product_ids" validate:"required_without=Selector,excluded_with=Selector,omitempty,dive,required"`
+	Selector   *ProductSelector `json:"selector" validate:"required_without=ProductIDs,excluded_with=ProductIDs,omitempty"`
+}
+
+// ProductSelector picks products of a restaurant's current menu by category
+// or by attribute.
+type ProductSelector struct {
+	RestaurantID string `json:"restaurant_id" validate:"required"`
+	Category     string `json:"category" validate:"required_without=AttributeID,excluded_with=AttributeID"`
+	AttributeID  string `json:"attribute_id" validate:"required_without=Category,excluded_with=Category"`
+}
+
+type ProductRequest struct {
+	Name        string   `json:"name" validate:"required"`
+	IsCombo     bool     `json:"is_combo"`
+	Price       float64  `json:"price" validate:"gte=0"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Status      string   `json:"status" validate:"omitempty,oneof=available not_available"`
+	Attributes  []string `json:"attributes"`
+	UserID      string   `json:"user_id,omitempty"`
+}
+
+// UpdateProductStatusRequest changes a product status. With Until set the
+// previous status is restored automatically at that time.
 type UpdateProductStatusRequest struct {
-	Status string `json:"status" validate:"required,oneof=available not_available deleted"`
-	Reason string `json:"reason"`
-	UserID string `json:"user_id,omitempty"`
+	Status string     `json:"status" validate:"required,oneof=available not_available deleted"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until,omitempty"`
+	UserID string     `json:"user_id,omitempty"`
 }
 
 // updateProductStatusHandler godoc
 //
 //	@Summary		Update product status
-//	@Description	Update the status of a product
+//	@Description	Update the status of a product. The product ID must belong to a single restaurant, use the restaurant-scoped route otherwise
 //	@Tags			products
 //	@Accept			json
 //	@Produce		json
@@ -29,9 +63,39 @@ type UpdateProductStatusRequest struct {
 //	@Success		202			{object}	map[string]interface{}
 //	@Failure		400			{object}	map[string]string
 //	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
 //	@Failure		500			{object}	map[string]string
 //	@Router			/products/{product_id}/status [patch]
 func (app *application) updateProductStatusHandler(w http.ResponseWriter, r *http.Request) {
+	app.updateProductStatus(w, r, "", "")
+}
+
+// updateRestaurantProductStatusHandler godoc
+//
+//	@Summary		Update restaurant product status
+//	@Description	Update the status of a product in the current menu of a restaurant and all its branches
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string						true	"Restaurant ID"
+//	@Param			product_id		path		string						true	"Product ID"
+//	@Param			request			body		UpdateProductStatusRequest	true	"Status update request"
+//	@Success		202				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/products/{product_id}/status [patch]
+func (app *application) updateRestaurantProductStatusHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID := chi.URLParam(r, "restaurant_id")
+	if restaurantID == "" {
+		app.badRequestResponse(w, r, errors.New("restaurant_id is required"))
+		return
+	}
+
+	app.updateProductStatus(w, r, restaurantID, "")
+}
+
+func (app *application) updateProductStatus(w http.ResponseWriter, r *http.Request, restaurantID, branchID string) {
 	productID := chi.URLParam(r, "product_id")
 	if productID == "" {
 		app.badRequestResponse(w, r, errors.New("product_id is required"))
@@ -49,14 +113,19 @@ func (app *application) updateProductStatusHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
+	if req.Until != nil && !req.Until.After(time.Now()) {
+		app.badRequestResponse(w, r, errors.New("until must be in the future"))
+		return
+	}
+
 	// use default user_id if not provided
 	userID := req.UserID
 	if userID == "" {
 		userID = "admin_123"
 	}
 
-	if err := app.productService.UpdateProductStatus(r.Context(), productID, req.Status, req.Reason, userID); err != nil {
-		app.internalServerError(w, r, err)
+	if err := app.productService.UpdateProductStatus(r.Context(), restaurantID, branchID, productID, req.Status, req.Reason, userID, req.Until); err != nil {
+		app.domainErrorResponse(w, r, err)
 		return
 	}
 
@@ -69,3 +138,235 @@ func (app *application) updateProductStatusHandler(w http.ResponseWriter, r *htt
 		app.internalServerError(w, r, err)
 	}
 }
+
+// batchUpdateProductStatusHandler godoc
+//
+//	@Summary		Batch update product status
+//	@Description	Update the status of several products selected by IDs or by a selector
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		BatchProductStatusRequest	true	"Batch status update request"
+//	@Success		202		{object}	[]service.BatchStatusResult
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/products/status:batch [patch]
+func (app *application) batchUpdateProductStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var req BatchProductStatusRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	selector := service.ProductSelector{ProductIDs: req.ProductIDs}
+	if req.Selector != nil {
+		selector.RestaurantID = req.Selector.RestaurantID
+		selector.Category = req.Selector.Category
+		selector.AttributeID = req.Selector.AttributeID
+	}
+
+	results, err := app.productService.BatchUpdateProductStatus(r.Context(), selector, req.Status, req.Reason, userID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusAccepted, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createProductHandler godoc
+//
+//	@Summary		Create product
+//	@Description	Queues creation of a product in a menu
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id		path		string			true	"Menu ID"
+//	@Param			product_id	path		string			true	"Product ID"
+//	@Param			request		body		ProductRequest	true	"Product"
+//	@Param			If-Match	header		string			true	"Menu ETag"
+//	@Success		202			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products/{product_id} [post]
+func (app *application) createProductHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, version, product, userID, ok := app.readProductRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.productService.CreateProduct(r.Context(), menuID, version, product, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Product creation queued",
+	}
+
+	if err := app.jsonRespone(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateProductHandler godoc
+//
+//	@Summary		Update product
+//	@Description	Queues replacement of a product in a menu. The status is kept, it is changed through the product status endpoints
+//	@Tags			products
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id		path		string			true	"Menu ID"
+//	@Param			product_id	path		string			true	"Product ID"
+//	@Param			request		body		ProductRequest	true	"Product"
+//	@Param			If-Match	header		string			true	"Menu ETag"
+//	@Success		202			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products/{product_id} [put]
+func (app *application) updateProductHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, version, product, userID, ok := app.readProductRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.productService.UpdateProduct(r.Context(), menuID, version, product, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Product update queued",
+	}
+
+	if err := app.jsonRespone(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteProductHandler godoc
+//
+//	@Summary		Delete product
+//	@Description	Queues removal of a product from a menu
+//	@Tags			products
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			product_id	path		string	true	"Product ID"
+//	@Param			user_id		query		string	false	"User ID"
+//	@Param			If-Match	header		string	true	"Menu ETag"
+//	@Success		202			{object}	map[string]interface{}
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		409			{object}	map[string]string
+//	@Failure		428			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products/{product_id} [delete]
+func (app *application) deleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	productID := chi.URLParam(r, "product_id")
+	if productID == "" {
+		app.badRequestResponse(w, r, errors.New("product_id is required"))
+		return
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.productService.DeleteProduct(r.Context(), menuID, version, productID, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success": true,
+		"message": "Product deletion queued",
+	}
+
+	if err := app.jsonRespone(w, http.StatusAccepted, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// readProductRequest reads path params and body shared by product create and update.
+func (app *application) readProductRequest(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, int64, domain.Product, string, bool) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return primitive.NilObjectID, 0, domain.Product{}, "", false
+	}
+
+	productID := chi.URLParam(r, "product_id")
+	if productID == "" {
+		app.badRequestResponse(w, r, errors.New("product_id is required"))
+		return primitive.NilObjectID, 0, domain.Product{}, "", false
+	}
+
+	version, ok := app.readIfMatch(w, r)
+	if !ok {
+		return primitive.NilObjectID, 0, domain.Product{}, "", false
+	}
+
+	var req ProductRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return primitive.NilObjectID, 0, domain.Product{}, "", false
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return primitive.NilObjectID, 0, domain.Product{}, "", false
+	}
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	product := domain.Product{
+		ID:          productID,
+		Name:        req.Name,
+		IsCombo:     req.IsCombo,
+		Price:       req.Price,
+		Category:    req.Category,
+		Description: req.Description,
+		Status:      req.Status,
+		Attributes:  req.Attributes,
+	}
+
+	return menuID, version, product, userID, true
+}
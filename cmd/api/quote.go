@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+type QuoteRequest struct {
+	ProductID  string                  `json:"product_id" validate:"required"`
+	Quantity   int                     `json:"quantity" validate:"omitempty,gte=1"`
+	Attributes []QuoteAttributeRequest `json:"attributes" validate:"dive"`
+}
+
+type QuoteAttributeRequest struct {
+	AttributeID string `json:"attribute_id" validate:"required"`
+	GroupID     string `json:"group_id,omitempty"`
+	Quantity    int    `json:"quantity" validate:"omitempty,gte=1"`
+}
+
+// quoteHandler godoc
+//
+//	@Summary		Quote order line
+//	@Description	Validates selected attributes against the product's attribute groups and returns an itemised price
+//	@Tags			menus
+//	@Accept			json
+//	@Produce		json
+//	@Param			menu_id	path		string			true	"Menu ID"
+//	@Param			request	body		QuoteRequest	true	"Order line"
+//	@Success		200		{object}	domain.Quote
+//	@Failure		400		{object}	map[string]string
+//	@Failure		404		{object}	map[string]string
+//	@Failure		500		{object}	map[string]string
+//	@Router			/menus/{menu_id}/quote [post]
+func (app *application) quoteHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var req QuoteRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	quote, err := app.menuService.Quote(r.Context(), menuID, req.toDomain())
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, quote); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (req QuoteRequest) toDomain() domain.QuoteRequest {
+	quoteReq := domain.QuoteRequest{
+		ProductID:  req.ProductID,
+		Quantity:   req.Quantity,
+		Selections: make([]domain.QuoteSelection, 0, len(req.Attributes)),
+	}
+	for _, attribute := range req.Attributes {
+		quoteReq.Selections = append(quoteReq.Selections, domain.QuoteSelection{
+			AttributeID: attribute.AttributeID,
+			GroupID:     attribute.GroupID,
+			Quantity:    attribute.Quantity,
+		})
+	}
+
+	return quoteReq
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// listMenuProductsHandler godoc
+//
+//	@Summary		List menu products
+//	@Description	List products of a menu filtered by category, status, combo flag, price range and text
+//	@Tags			products
+//	@Produce		json
+//	@Param			menu_id		path		string	true	"Menu ID"
+//	@Param			q			query		string	false	"Text to search in name and description"
+//	@Param			category	query		string	false	"Category"
+//	@Param			status		query		string	false	"Status"
+//	@Param			is_combo	query		bool	false	"Combo flag"
+//	@Param			min_price	query		number	false	"Minimum price"
+//	@Param			max_price	query		number	false	"Maximum price"
+//	@Param			limit		query		int		false	"Maximum number of products"
+//	@Param			fields		query		string	false	"Comma-separated product fields to return"
+//	@Success		200			{object}	[]domain.Product
+//	@Failure		400			{object}	map[string]string
+//	@Failure		404			{object}	map[string]string
+//	@Failure		500			{object}	map[string]string
+//	@Router			/menus/{menu_id}/products [get]
+func (app *application) listMenuProductsHandler(w http.ResponseWriter, r *http.Request) {
+	menuID, err := objectIDParam(r, "menu_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var products any
+	if fields := parseFields(r.URL.Query()); len(fields) > 0 {
+		products, err = app.menuService.ListProductFields(r.Context(), menuID, filter, fields)
+	} else {
+		products, err = app.menuService.ListProducts(r.Context(), menuID, filter)
+	}
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, products); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// searchProductsHandler godoc
+//
+//	@Summary		Search products
+//	@Description	Search products across the current menus of all restaurants
+//	@Tags			products
+//	@Produce		json
+//	@Param			q				query		string	false	"Text to search in name and description"
+//	@Param			restaurant_id	query		string	false	"Restaurant ID"
+//	@Param			category		query		string	false	"Category"
+//	@Param			status			query		string	false	"Status"
+//	@Param			is_combo		query		bool	false	"Combo flag"
+//	@Param			min_price		query		number	false	"Minimum price"
+//	@Param			max_price		query		number	false	"Maximum price"
+//	@Param			limit			query		int		false	"Maximum number of products"
+//	@Param			fields			query		string	false	"Comma-separated product fields to return"
+//	@Success		200				{object}	[]domain.ProductSearchResult
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/products [get]
+func (app *application) searchProductsHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseProductFilter(r.URL.Query())
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var results any
+	if fields := parseFields(r.URL.Query()); len(fields) > 0 {
+		results, err = app.menuService.SearchProductFields(r.Context(), filter, fields)
+	} else {
+		results, err = app.menuService.SearchProducts(r.Context(), filter)
+	}
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, results); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func parseProductFilter(query url.Values) (domain.ProductFilter, error) {
+	filter := domain.ProductFilter{
+		Query:        query.Get("q"),
+		RestaurantID: query.Get("restaurant_id"),
+		Category:     query.Get("category"),
+		Status:       query.Get("status"),
+		Limit:        defaultSearchLimit,
+	}
+
+	if v := query.Get("is_combo"); v != "" {
+		isCombo, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid is_combo: %q", v)
+		}
+		filter.IsCombo = &isCombo
+	}
+
+	if v := query.Get("min_price"); v != "" {
+		minPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid min_price: %q", v)
+		}
+		filter.MinPrice = &minPrice
+	}
+
+	if v := query.Get("max_price"); v != "" {
+		maxPrice, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid max_price: %q", v)
+		}
+		filter.MaxPrice = &maxPrice
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxSearchLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxSearchLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
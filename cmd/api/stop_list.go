@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/go-chi/chi"
+)
+
+// stopListHandler godoc
+//
+//	@Summary		Get restaurant stop-list
+//	@Description	Lists unavailable products of the restaurant's current menu with the reason, user and time of their last status change. CSV is returned for format=csv or an Accept header with text/csv
+//	@Tags			products
+//	@Produce		json
+//	@Produce		text/csv
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			format			query		string	false	"Output format"	Enums(json, csv)
+//	@Success		200				{object}	[]domain.StopListEntry
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/stop-list [get]
+func (app *application) stopListHandler(w http.ResponseWriter, r *http.Request) {
+	restaurantID := chi.URLParam(r, "restaurant_id")
+	if restaurantID == "" {
+		app.badRequestResponse(w, r, errors.New("restaurant_id is required"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		format = "csv"
+	}
+	if format != "" && format != "json" && format != "csv" {
+		app.badRequestResponse(w, r, fmt.Errorf("unsupported format %q", format))
+		return
+	}
+
+	entries, err := app.productService.StopList(r.Context(), restaurantID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if format == "csv" {
+		if err := writeStopListCSV(w, restaurantID, entries); err != nil {
+			app.logger.Errorw("failed to write stop-list", "restaurant_id", restaurantID, "error", err)
+		}
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, entries); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func writeStopListCSV(w http.ResponseWriter, restaurantID string, entries []domain.StopListEntry) error {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "stop-list-"+restaurantID+".csv"))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"product_id", "name", "category", "status", "reason", "user_id", "changed_at"}); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		var changedAt string
+		if entry.ChangedAt != nil {
+			changedAt = entry.ChangedAt.UTC().Format(time.RFC3339)
+		}
+
+		record := []string{entry.ProductID, entry.Name, entry.Category, entry.Status, entry.Reason, entry.UserID, changedAt}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
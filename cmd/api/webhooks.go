@@ -0,0 +1,269 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/go-chi/chi"
+)
+
+type WebhookRequest struct {
+	URL        string   `json:"url" validate:"required,url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+	UserID     string   `json:"user_id,omitempty"`
+}
+
+// webhook returns the webhook described by the request. Webhooks are active
+// unless the request says otherwise.
+func (req WebhookRequest) webhook(restaurantID string) *domain.Webhook {
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+
+	return &domain.Webhook{
+		RestaurantID: restaurantID,
+		URL:          req.URL,
+		EventTypes:   req.EventTypes,
+		Secret:       req.Secret,
+		Active:       active,
+	}
+}
+
+// listWebhooksHandler godoc
+//
+//	@Summary		List webhooks
+//	@Description	Lists the webhook subscriptions of a restaurant. Secrets are not returned
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Success		200				{object}	[]domain.Webhook
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks [get]
+func (app *application) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := app.webhookService.ListWebhooks(r.Context(), chi.URLParam(r, "restaurant_id"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, webhooks); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// createWebhookHandler godoc
+//
+//	@Summary		Create webhook
+//	@Description	Subscribes a URL to events of a restaurant: product.created, product.updated, product.status_changed, product.deleted, attribute.status_changed and menu.imported. No event types subscribes to all of them. Deliveries are signed with the secret (at least 16 characters)
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string			true	"Restaurant ID"
+//	@Param			request			body		WebhookRequest	true	"Webhook"
+//	@Success		201				{object}	domain.Webhook
+//	@Failure		400				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks [post]
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var req WebhookRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := req.webhook(chi.URLParam(r, "restaurant_id"))
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.webhookService.CreateWebhook(r.Context(), webhook, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusCreated, webhook); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// getWebhookHandler godoc
+//
+//	@Summary		Get webhook
+//	@Description	Get a webhook subscription of a restaurant
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			webhook_id		path		string	true	"Webhook ID"
+//	@Success		200				{object}	domain.Webhook
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks/{webhook_id} [get]
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := objectIDParam(r, "webhook_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook, err := app.webhookService.GetWebhook(r.Context(), chi.URLParam(r, "restaurant_id"), webhookID)
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, webhook); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// updateWebhookHandler godoc
+//
+//	@Summary		Update webhook
+//	@Description	Replaces the URL, event types and active flag of a webhook. An empty secret keeps the current one. Queued deliveries are sent with the new URL and secret
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			restaurant_id	path		string			true	"Restaurant ID"
+//	@Param			webhook_id		path		string			true	"Webhook ID"
+//	@Param			request			body		WebhookRequest	true	"Webhook"
+//	@Success		200				{object}	domain.Webhook
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks/{webhook_id} [put]
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := objectIDParam(r, "webhook_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var req WebhookRequest
+	if err := readJson(w, r, &req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhook := req.webhook(chi.URLParam(r, "restaurant_id"))
+	webhook.ID = webhookID
+
+	// use default user_id if not provided
+	userID := req.UserID
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.webhookService.UpdateWebhook(r.Context(), webhook, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, webhook); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// deleteWebhookHandler godoc
+//
+//	@Summary		Delete webhook
+//	@Description	Removes a webhook subscription. Its queued deliveries fail without being sent, the delivery log is kept
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			webhook_id		path		string	true	"Webhook ID"
+//	@Param			user_id			query		string	false	"User ID"
+//	@Success		200				{object}	map[string]interface{}
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks/{webhook_id} [delete]
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := objectIDParam(r, "webhook_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		userID = "admin_123"
+	}
+
+	if err := app.webhookService.DeleteWebhook(r.Context(), chi.URLParam(r, "restaurant_id"), webhookID, userID); err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, map[string]interface{}{"success": true}); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// listWebhookDeliveriesHandler godoc
+//
+//	@Summary		List webhook deliveries
+//	@Description	Lists deliveries of a webhook newest first with the log of their attempts. Pass next_cursor of a page as cursor to get the next one
+//	@Tags			webhooks
+//	@Produce		json
+//	@Param			restaurant_id	path		string	true	"Restaurant ID"
+//	@Param			webhook_id		path		string	true	"Webhook ID"
+//	@Param			state			query		string	false	"Delivery state"	Enums(pending, delivered, failed)
+//	@Param			limit			query		int		false	"Page size"
+//	@Param			cursor			query		string	false	"Cursor of the next page"
+//	@Success		200				{object}	domain.WebhookDeliveryPage
+//	@Failure		400				{object}	map[string]string
+//	@Failure		404				{object}	map[string]string
+//	@Failure		500				{object}	map[string]string
+//	@Router			/restaurants/{restaurant_id}/webhooks/{webhook_id}/deliveries [get]
+func (app *application) listWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhookID, err := objectIDParam(r, "webhook_id")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := domain.WebhookDeliveryFilter{
+		WebhookID: webhookID,
+		State:     query.Get("state"),
+		Limit:     defaultAuditLimit,
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAuditLimit {
+			app.badRequestResponse(w, r, fmt.Errorf("limit must be between 1 and %d", maxAuditLimit))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := app.webhookService.ListDeliveries(r.Context(), chi.URLParam(r, "restaurant_id"), filter, query.Get("cursor"))
+	if err != nil {
+		app.domainErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.jsonRespone(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/env"
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// audit-restore loads product status audit archives back into MongoDB:
+//
+//	go run cmd/audit-restore/main.go archive/audit/product_status_audit-*.ndjson.gz
+//
+// Restoring an archive twice is safe, records are upserted by ID.
+func main() {
+	_ = godotenv.Load()
+
+	logger := zap.Must(zap.NewProduction()).Sugar()
+	defer logger.Sync()
+
+	paths := os.Args[1:]
+	if len(paths) == 0 {
+		logger.Fatal("usage: audit-restore <archive.ndjson.gz>...")
+	}
+
+	storage, err := mongo.New(mongo.Config{
+		URI:      env.GetString("MONGO_URI", "mongodb://localhost:27017"),
+		Database: env.GetString("MONGO_DATABASE", "kwaaka"),
+		Timeout:  time.Second * 10,
+	})
+	if err != nil {
+		logger.Fatalw("failed to connect to MongoDB", "error", err)
+	}
+	defer storage.Close(context.Background())
+
+	auditRepo := mongo.NewProductStatusAuditRepository(storage.Database())
+	archiveService := service.NewAuditArchiveService(auditRepo, env.GetString("AUDIT_ARCHIVE_DIR", "./archive/audit"), logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	for _, path := range paths {
+		restored, err := archiveService.Restore(ctx, path)
+		if err != nil {
+			logger.Fatalw("failed to restore audit archive", "path", path, "restored", restored, "error", err)
+		}
+	}
+}
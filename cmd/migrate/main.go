@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/env"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+)
+
+// migrate brings existing MongoDB data to the current schema. Run it before
+// starting a new API version.
+func main() {
+	_ = godotenv.Load()
+
+	logger := zap.Must(zap.NewProduction()).Sugar()
+	defer logger.Sync()
+
+	storage, err := mongo.New(mongo.Config{
+		URI:      env.GetString("MONGO_URI", "mongodb://localhost:27017"),
+		Database: env.GetString("MONGO_DATABASE", "kwaaka"),
+		Timeout:  time.Second * 10,
+	})
+	if err != nil {
+		logger.Fatalw("failed to connect to MongoDB", "error", err)
+	}
+	defer storage.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if err := storage.CreateIndexes(ctx); err != nil {
+		logger.Fatalw("failed to create indexes", "error", err)
+	}
+
+	migrated, err := storage.MigrateEmbeddedProducts(ctx)
+	if err != nil {
+		logger.Fatalw("failed to migrate embedded products", "migrated_menus", migrated, "error", err)
+	}
+
+	logger.Infow("embedded products migrated", "migrated_menus", migrated)
+
+	updated, err := storage.MigrateAttributeStatuses(ctx)
+	if err != nil {
+		logger.Fatalw("failed to migrate attribute statuses", "error", err)
+	}
+
+	logger.Infow("attribute statuses migrated", "updated_menus", updated)
+
+	copied, err := storage.MigrateMenuAudit(ctx)
+	if err != nil {
+		logger.Fatalw("failed to migrate menu audit", "copied_records", copied, "error", err)
+	}
+
+	logger.Infow("menu audit migrated", "copied_records", copied)
+}
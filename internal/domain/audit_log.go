@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EntityMenu           = "menu"
+	EntityProduct        = "product"
+	EntityAttribute      = "attribute"
+	EntityAttributeGroup = "attribute_group"
+	EntityBranch         = "branch"
+	EntityParsingTask    = "parsing_task"
+	EntityWebhook        = "webhook"
+)
+
+const (
+	MenuActionImported = "menu.imported"
+	MenuActionDeleted  = "menu.deleted"
+	MenuActionRestored = "menu.restored"
+	MenuActionPurged   = "menu.purged"
+
+	ProductActionCreated       = "product.created"
+	ProductActionUpdated       = "product.updated"
+	ProductActionDeleted       = "product.deleted"
+	ProductActionStatusChanged = "product.status_changed"
+	ProductActionGroupLinked   = "product.attribute_group_linked"
+	ProductActionGroupUnlinked = "product.attribute_group_unlinked"
+
+	AttributeActionCreated       = "attribute.created"
+	AttributeActionUpdated       = "attribute.updated"
+	AttributeActionDeleted       = "attribute.deleted"
+	AttributeActionStatusChanged = "attribute.status_changed"
+
+	AttributeGroupActionCreated = "attribute_group.created"
+	AttributeGroupActionUpdated = "attribute_group.updated"
+	AttributeGroupActionDeleted = "attribute_group.deleted"
+
+	BranchActionCreated      = "branch.created"
+	BranchActionDeleted      = "branch.deleted"
+	BranchActionPriceChanged = "branch.price_changed"
+
+	ParsingTaskActionCreated = "parsing_task.created"
+	ParsingTaskActionRetried = "parsing_task.retried"
+
+	WebhookActionCreated = "webhook.created"
+	WebhookActionUpdated = "webhook.updated"
+	WebhookActionDeleted = "webhook.deleted"
+)
+
+// AuditLogEntry records a change of an entity: who made it, what it was and
+// snapshots of the changed fields before and after it. Before is empty for
+// created entities, After for deleted ones.
+type AuditLogEntry struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Actor        string             `bson:"actor" json:"actor"`
+	Action       string             `bson:"action" json:"action"`
+	EntityType   string             `bson:"entity_type" json:"entity_type"`
+	EntityID     string             `bson:"entity_id" json:"entity_id"`
+	RestaurantID string             `bson:"restaurant_id,omitempty" json:"restaurant_id,omitempty"`
+	MenuID       primitive.ObjectID `bson:"menu_id,omitempty" json:"menu_id,omitempty"`
+	Before       bson.M             `bson:"before,omitempty" json:"before,omitempty"`
+	After        bson.M             `bson:"after,omitempty" json:"after,omitempty"`
+	Timestamp    time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// AuditLogFilter narrows audit log queries. Entries are returned newest
+// first, AfterTimestamp and AfterID are the position of the last entry of the
+// previous page. Empty fields are not applied.
+type AuditLogFilter struct {
+	EntityType     string
+	EntityID       string
+	RestaurantID   string
+	Actor          string
+	Action         string
+	From           *time.Time
+	To             *time.Time
+	AfterTimestamp *time.Time
+	AfterID        primitive.ObjectID
+	Limit          int
+}
+
+// AuditLogPage is a page of the audit log. NextCursor is empty on the last
+// page.
+type AuditLogPage struct {
+	Items      []AuditLogEntry `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
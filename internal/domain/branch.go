@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Branch is a location of a restaurant. Branches share the restaurant's
+// current menu and override prices and statuses on top of it. An empty Status
+// or a nil Price inherits the value of the menu.
+type Branch struct {
+	ID           string              `bson:"id" json:"id"`
+	RestaurantID string              `bson:"restaurant_id" json:"restaurant_id"`
+	Name         string              `bson:"name" json:"name"`
+	Products     []ProductOverride   `bson:"products" json:"products"`
+	Attributes   []AttributeOverride `bson:"attributes" json:"attributes"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+type ProductOverride struct {
+	ProductID string   `bson:"product_id" json:"product_id"`
+	Price     *float64 `bson:"price,omitempty" json:"price,omitempty"`
+	Status    string   `bson:"status,omitempty" json:"status,omitempty"`
+}
+
+type AttributeOverride struct {
+	AttributeID string   `bson:"attribute_id" json:"attribute_id"`
+	Price       *float64 `bson:"price,omitempty" json:"price,omitempty"`
+	Status      string   `bson:"status,omitempty" json:"status,omitempty"`
+}
+
+func (b *Branch) ProductOverride(productID string) *ProductOverride {
+	for i := range b.Products {
+		if b.Products[i].ProductID == productID {
+			return &b.Products[i]
+		}
+	}
+	return nil
+}
+
+func (b *Branch) AttributeOverride(attributeID string) *AttributeOverride {
+	for i := range b.Attributes {
+		if b.Attributes[i].AttributeID == attributeID {
+			return &b.Attributes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package domain
+
+import "errors"
+
+var (
+	ErrMenuNotFound    = errors.New("menu not found")
+	ErrVersionConflict = errors.New("menu has been modified, version is stale")
+	ErrInvalidFields   = errors.New("invalid fields")
+	ErrProductNotFound = errors.New("product not found")
+	ErrProductExists   = errors.New("product already exists")
+	ErrInvalidProduct  = errors.New("invalid product")
+
+	ErrProductAmbiguous = errors.New("product ID matches more than one restaurant")
+
+	ErrProductUnavailable = errors.New("product is not available")
+	ErrInvalidQuote       = errors.New("invalid quote")
+
+	ErrAttributeGroupNotFound = errors.New("attribute group not found")
+	ErrAttributeGroupExists   = errors.New("attribute group already exists")
+	ErrAttributeGroupInUse    = errors.New("attribute group is attached to a product")
+	ErrInvalidAttributeGroup  = errors.New("invalid attribute group")
+
+	ErrAttributeNotFound = errors.New("attribute not found")
+	ErrAttributeExists   = errors.New("attribute already exists")
+	ErrAttributeInUse    = errors.New("attribute is used by an attribute group")
+	ErrInvalidAttribute  = errors.New("invalid attribute")
+
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	ErrBranchNotFound = errors.New("branch not found")
+	ErrBranchExists   = errors.New("branch already exists")
+
+	ErrAuditLogEntryNotFound = errors.New("audit log entry not found")
+
+	ErrEventAlreadyProcessed = errors.New("event already processed")
+
+	ErrTaskAlreadyCompleted = errors.New("parsing task is already completed")
+
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+)
@@ -8,14 +8,39 @@ type MenuParsingMessage struct {
 	RestaurantName string `json:"restaurant_name"`
 }
 
+// ProductStatusEvent is published to the product-status queue. RestaurantID
+// and MenuID scope single product events, Items are set for batch status
+// changes. Update and delete events carry the product as it was in
+// OldProduct. Attribute status changes carry AttributeID instead of
+// ProductID. Status changes with BranchID set override the status in that
+// branch only. Status changes carry the Sequence number issued when they were
+// requested, batch changes one per item, product create events one for the
+// initial status.
 type ProductStatusEvent struct {
-	EventType string    `json:"event_type"`
-	ProductID string    `json:"product_id"`
-	OldStatus string    `json:"old_status"`
-	NewStatus string    `json:"new_status"`
-	Reason    string    `json:"reason"`
-	Timestamp time.Time `json:"timestamp"`
-	UserID    string    `json:"user_id"`
+	ID           string                   `json:"id,omitempty"`
+	EventType    string                   `json:"event_type"`
+	Sequence     int64                    `json:"sequence,omitempty"`
+	RestaurantID string                   `json:"restaurant_id,omitempty"`
+	MenuID       string                   `json:"menu_id,omitempty"`
+	ProductID    string                   `json:"product_id"`
+	AttributeID  string                   `json:"attribute_id,omitempty"`
+	BranchID     string                   `json:"branch_id,omitempty"`
+	Product      *Product                 `json:"product,omitempty"`
+	OldProduct   *Product                 `json:"old_product,omitempty"`
+	Items        []ProductStatusEventItem `json:"items,omitempty"`
+	OldStatus    string                   `json:"old_status"`
+	NewStatus    string                   `json:"new_status"`
+	Reason       string                   `json:"reason"`
+	Timestamp    time.Time                `json:"timestamp"`
+	UserID       string                   `json:"user_id"`
+}
+
+type ProductStatusEventItem struct {
+	RestaurantID string `json:"restaurant_id,omitempty"`
+	MenuID       string `json:"menu_id"`
+	ProductID    string `json:"product_id"`
+	Sequence     int64  `json:"sequence,omitempty"`
+	OldStatus    string `json:"old_status"`
 }
 
 const (
@@ -23,4 +48,12 @@ const (
 	EventProductUpdated       = "product.updated"
 	EventProductStatusChanged = "product.status_changed"
 	EventProductDeleted       = "product.deleted"
+
+	EventProductStatusBatchChanged = "product.status_batch_changed"
+
+	EventAttributeStatusChanged = "attribute.status_changed"
+
+	// EventMenuImported is sent to webhooks when a parsed menu is saved, it
+	// is not published to the queue
+	EventMenuImported = "menu.imported"
 )
@@ -6,15 +6,31 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	ProductStatusAvailable    = "available"
+	ProductStatusNotAvailable = "not_available"
+	ProductStatusDeleted      = "deleted"
+)
+
+const (
+	AttributeStatusAvailable    = "available"
+	AttributeStatusNotAvailable = "not_available"
+)
+
+// Menu is versioned: Version is incremented on every write. Products are
+// stored in their own collection and filled in on read. Deleted menus keep
+// DeletedAt until they are purged and are hidden from reads.
 type Menu struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name            string             `bson:"name" json:"name"`
 	RestaurantID    string             `bson:"restaurant_id" json:"restaurant_id"`
-	Products        []Product          `bson:"products" json:"products"`
+	Products        []Product          `bson:"products,omitempty" json:"products"`
 	AttributeGroups []AttributeGroup   `bson:"attributes_groups" json:"attributes_groups"`
 	Attributes      []Attribute        `bson:"attributes" json:"attributes"`
+	Version         int64              `bson:"version" json:"version"`
 	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt       *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 }
 
 type Product struct {
@@ -37,9 +53,36 @@ type AttributeGroup struct {
 }
 
 type Attribute struct {
-	ID    string  `bson:"id" json:"id"`
-	Name  string  `bson:"name" json:"name"`
-	Min   int     `bson:"min" json:"min"`
-	Max   int     `bson:"max" json:"max"`
-	Price float64 `bson:"price" json:"price"`
+	ID     string  `bson:"id" json:"id"`
+	Name   string  `bson:"name" json:"name"`
+	Min    int     `bson:"min" json:"min"`
+	Max    int     `bson:"max" json:"max"`
+	Price  float64 `bson:"price" json:"price"`
+	Status string  `bson:"status" json:"status"`
+}
+
+// Available reports whether the attribute can be ordered. Attributes stored
+// before they had a status are available.
+func (a Attribute) Available() bool {
+	return a.Status == "" || a.Status == AttributeStatusAvailable
+}
+
+// ProductFilter narrows product listings and searches. Nil pointers and empty
+// strings are not applied.
+type ProductFilter struct {
+	Query        string
+	RestaurantID string
+	Category     string
+	Status       string
+	IsCombo      *bool
+	MinPrice     *float64
+	MaxPrice     *float64
+	Limit        int
+}
+
+type ProductSearchResult struct {
+	MenuID       primitive.ObjectID `bson:"menu_id" json:"menu_id"`
+	MenuName     string             `bson:"menu_name" json:"menu_name"`
+	RestaurantID string             `bson:"restaurant_id" json:"restaurant_id"`
+	Product      Product            `bson:"product" json:"product"`
 }
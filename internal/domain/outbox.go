@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+)
+
+// OutboxMessage is a broker message stored in the same transaction as the
+// change that produced it and published afterwards by the outbox relay.
+// AvailableAt is when the message may be claimed next, it is pushed forward
+// while a relay holds the message.
+type OutboxMessage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Queue       string             `bson:"queue" json:"queue"`
+	Payload     []byte             `bson:"payload" json:"payload"`
+	State       string             `bson:"state" json:"state"`
+	Attempts    int                `bson:"attempts" json:"attempts"`
+	LastError   string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	AvailableAt time.Time          `bson:"available_at" json:"available_at"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	SentAt      *time.Time         `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+}
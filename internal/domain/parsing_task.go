@@ -16,13 +16,23 @@ const (
 )
 
 type ParsingTask struct {
-	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	Status         ParsingTaskStatus   `bson:"status" json:"status"`
-	SpreadsheetID  string              `bson:"spreadsheet_id" json:"spreadsheet_id"`
-	RestaurantName string              `bson:"restaurant_name" json:"restaurant_name"`
-	MenuID         *primitive.ObjectID `bson:"menu_id,omitempty" json:"menu_id,omitempty"`
-	ErrorMessage   string              `bson:"error_message,omitempty" json:"error_message,omitempty"`
-	RetryCount     int                 `bson:"retry_count" json:"retry_count"`
-	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Status          ParsingTaskStatus   `bson:"status" json:"status"`
+	SpreadsheetID   string              `bson:"spreadsheet_id" json:"spreadsheet_id"`
+	RestaurantName  string              `bson:"restaurant_name" json:"restaurant_name"`
+	MenuID          *primitive.ObjectID `bson:"menu_id,omitempty" json:"menu_id,omitempty"`
+	ErrorMessage    string              `bson:"error_message,omitempty" json:"error_message,omitempty"`
+	RetryCount      int                 `bson:"retry_count" json:"retry_count"`
+	ResetStatuses   bool                `bson:"reset_statuses" json:"reset_statuses"`
+	UserID          string              `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	CarriedStatuses []CarriedStatus     `bson:"carried_statuses,omitempty" json:"carried_statuses,omitempty"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CarriedStatus is a non-default product status copied from the previous menu
+// of the restaurant during re-import.
+type CarriedStatus struct {
+	ProductID string `bson:"product_id" json:"product_id"`
+	Status    string `bson:"status" json:"status"`
 }
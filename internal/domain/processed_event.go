@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// ProcessedEvent records a broker event that has been applied, so a
+// redelivered copy of it is skipped. Records expire after a week.
+type ProcessedEvent struct {
+	ID          string    `bson:"_id" json:"id"`
+	EventType   string    `bson:"event_type" json:"event_type"`
+	ProcessedAt time.Time `bson:"processed_at" json:"processed_at"`
+}
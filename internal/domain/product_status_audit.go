@@ -6,13 +6,44 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ProductStatusAudit records a status change. Rejected changes arrived after
+// a newer change had been applied and were not made.
 type ProductStatusAudit struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	ProductID string             `bson:"product_id" json:"product_id"`
-	EventType string             `bson:"event_type" json:"event_type"`
-	OldStatus string             `bson:"old_status" json:"old_status"`
-	NewStatus string             `bson:"new_status" json:"new_status"`
-	Reason    string             `bson:"reason" json:"reason"`
-	UserID    string             `bson:"user_id" json:"user_id"`
-	Timestamp time.Time          `bson:"timestamp" json:"timestamp"`
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID string             `bson:"restaurant_id,omitempty" json:"restaurant_id,omitempty"`
+	MenuID       primitive.ObjectID `bson:"menu_id,omitempty" json:"menu_id"`
+	ProductID    string             `bson:"product_id" json:"product_id"`
+	AttributeID  string             `bson:"attribute_id,omitempty" json:"attribute_id,omitempty"`
+	BranchID     string             `bson:"branch_id,omitempty" json:"branch_id,omitempty"`
+	EventType    string             `bson:"event_type" json:"event_type"`
+	OldStatus    string             `bson:"old_status" json:"old_status"`
+	NewStatus    string             `bson:"new_status" json:"new_status"`
+	Reason       string             `bson:"reason" json:"reason"`
+	UserID       string             `bson:"user_id" json:"user_id"`
+	Rejected     bool               `bson:"rejected,omitempty" json:"rejected,omitempty"`
+	Timestamp    time.Time          `bson:"timestamp" json:"timestamp"`
+}
+
+// ProductStatusAuditFilter narrows the audit history of a product or a
+// restaurant. Records are returned newest first, AfterTimestamp and AfterID
+// are the position of the last record of the previous page. Nil pointers and
+// empty strings are not applied.
+type ProductStatusAuditFilter struct {
+	ProductID      string
+	RestaurantID   string
+	EventType      string
+	UserID         string
+	Reason         string
+	From           *time.Time
+	To             *time.Time
+	AfterTimestamp *time.Time
+	AfterID        primitive.ObjectID
+	Limit          int
+}
+
+// ProductStatusAuditPage is a page of audit history. NextCursor is empty on
+// the last page.
+type ProductStatusAuditPage struct {
+	Items      []ProductStatusAudit `json:"items"`
+	NextCursor string               `json:"next_cursor,omitempty"`
 }
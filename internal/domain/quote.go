@@ -0,0 +1,37 @@
+package domain
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type QuoteRequest struct {
+	ProductID  string
+	Quantity   int
+	Selections []QuoteSelection
+}
+
+// QuoteSelection is a chosen attribute. GroupID may be left empty when the
+// attribute belongs to exactly one of the product's groups.
+type QuoteSelection struct {
+	AttributeID string
+	GroupID     string
+	Quantity    int
+}
+
+type Quote struct {
+	MenuID      primitive.ObjectID `json:"menu_id"`
+	ProductID   string             `json:"product_id"`
+	ProductName string             `json:"product_name"`
+	Quantity    int                `json:"quantity"`
+	BasePrice   float64            `json:"base_price"`
+	Lines       []QuoteLine        `json:"lines"`
+	UnitPrice   float64            `json:"unit_price"`
+	Total       float64            `json:"total"`
+}
+
+type QuoteLine struct {
+	AttributeID string  `json:"attribute_id"`
+	GroupID     string  `json:"group_id"`
+	Name        string  `json:"name"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Total       float64 `json:"total"`
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RestorePending  = "pending"
+	RestoreFiring   = "firing"
+	RestoreDone     = "done"
+	RestoreSkipped  = "skipped"
+	RestoreCanceled = "canceled"
+)
+
+// ReasonAutoRestore is the reason of status changes published by scheduled
+// restores.
+const ReasonAutoRestore = "auto_restore"
+
+// ScheduledRestore reverts a temporary product status change at RunAt. Status
+// is the temporary status, RestoreStatus the one to return to. Sequence is the
+// number of the temporary change, a restore is skipped if a later change was
+// applied to the product meanwhile. Restores with BranchID set revert the
+// branch's status override. A firing restore is held by the scheduler that
+// claimed it until ClaimedUntil.
+type ScheduledRestore struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID  string             `bson:"restaurant_id" json:"restaurant_id"`
+	BranchID      string             `bson:"branch_id,omitempty" json:"branch_id,omitempty"`
+	ProductID     string             `bson:"product_id" json:"product_id"`
+	Status        string             `bson:"status" json:"status"`
+	RestoreStatus string             `bson:"restore_status" json:"restore_status"`
+	Sequence      int64              `bson:"sequence,omitempty" json:"sequence,omitempty"`
+	RunAt         time.Time          `bson:"run_at" json:"run_at"`
+	State         string             `bson:"state" json:"state"`
+	ClaimedUntil  *time.Time         `bson:"claimed_until,omitempty" json:"claimed_until,omitempty"`
+	UserID        string             `bson:"user_id" json:"user_id"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
+}
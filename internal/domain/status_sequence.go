@@ -0,0 +1,83 @@
+package domain
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// StatusSequence numbers the status changes of a product or attribute of a
+// restaurant in the order they were requested, so the worker can tell a
+// change delivered late from a newer one. EntityType is EntityProduct or
+// EntityAttribute. Issued is the number given to the latest requested change,
+// Applied the latest change applied to the whole restaurant and AppliedMax the
+// latest applied anywhere, in the restaurant or in a branch.
+type StatusSequence struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID string             `bson:"restaurant_id" json:"restaurant_id"`
+	EntityType   string             `bson:"entity_type" json:"entity_type"`
+	EntityID     string             `bson:"entity_id" json:"entity_id"`
+	Issued       int64              `bson:"issued" json:"issued"`
+	Applied      int64              `bson:"applied" json:"applied"`
+	AppliedMax   int64              `bson:"applied_max" json:"applied_max"`
+	Branches     []BranchSequence   `bson:"branches" json:"branches"`
+}
+
+// BranchSequence is the latest change applied only to a branch.
+type BranchSequence struct {
+	BranchID string `bson:"branch_id" json:"branch_id"`
+	Applied  int64  `bson:"applied" json:"applied"`
+}
+
+// Accept reports whether the change numbered seq is newer than every change
+// applied to what it affects, and records it as applied if so. A change
+// without a branch affects the restaurant and all its branches, so it is
+// stale once any later change has been applied. A branch change is stale once
+// a later change of the restaurant or of the same branch has been applied.
+func (s *StatusSequence) Accept(branchID string, seq int64) bool {
+	if branchID == "" {
+		if seq <= s.AppliedMax {
+			return false
+		}
+		s.Applied = seq
+		s.AppliedMax = seq
+		// older branch changes can't outrank this one anymore
+		s.Branches = []BranchSequence{}
+		return true
+	}
+
+	if seq <= s.Applied {
+		return false
+	}
+
+	found := false
+	for i := range s.Branches {
+		if s.Branches[i].BranchID != branchID {
+			continue
+		}
+		if seq <= s.Branches[i].Applied {
+			return false
+		}
+		s.Branches[i].Applied = seq
+		found = true
+	}
+	if !found {
+		s.Branches = append(s.Branches, BranchSequence{BranchID: branchID, Applied: seq})
+	}
+	s.AppliedMax = max(s.AppliedMax, seq)
+
+	return true
+}
+
+// AppliedTo returns the latest applied change that affects the restaurant, or
+// the branch if branchID is set.
+func (s *StatusSequence) AppliedTo(branchID string) int64 {
+	applied := s.Applied
+	if branchID == "" {
+		return applied
+	}
+
+	for _, branch := range s.Branches {
+		if branch.BranchID == branchID {
+			applied = max(applied, branch.Applied)
+		}
+	}
+
+	return applied
+}
@@ -0,0 +1,150 @@
+package domain
+
+import "testing"
+
+func TestStatusSequenceAccept(t *testing.T) {
+	type change struct {
+		branchID string
+		seq      int64
+		want     bool
+	}
+
+	tests := []struct {
+		name    string
+		changes []change
+	}{
+		{
+			name: "in order",
+			changes: []change{
+				{seq: 1, want: true},
+				{seq: 2, want: true},
+				{seq: 3, want: true},
+			},
+		},
+		{
+			name: "stale",
+			changes: []change{
+				{seq: 3, want: true},
+				{seq: 2, want: false},
+				{seq: 1, want: false},
+			},
+		},
+		{
+			name: "duplicate",
+			changes: []change{
+				{seq: 2, want: true},
+				{seq: 2, want: false},
+			},
+		},
+		{
+			name: "gap",
+			changes: []change{
+				{seq: 1, want: true},
+				{seq: 5, want: true},
+				{seq: 3, want: false},
+				{seq: 6, want: true},
+			},
+		},
+		{
+			name: "branches are ordered separately",
+			changes: []change{
+				{branchID: "a", seq: 2, want: true},
+				{branchID: "b", seq: 1, want: true},
+				{branchID: "a", seq: 1, want: false},
+				{branchID: "b", seq: 3, want: true},
+			},
+		},
+		{
+			name: "duplicate branch change",
+			changes: []change{
+				{branchID: "a", seq: 2, want: true},
+				{branchID: "a", seq: 2, want: false},
+			},
+		},
+		{
+			name: "restaurant change is stale after a later branch change",
+			changes: []change{
+				{branchID: "a", seq: 2, want: true},
+				{seq: 1, want: false},
+			},
+		},
+		{
+			name: "branch change is stale after a later restaurant change",
+			changes: []change{
+				{seq: 4, want: true},
+				{branchID: "a", seq: 3, want: false},
+				{branchID: "a", seq: 5, want: true},
+			},
+		},
+		{
+			name: "restaurant change resets branches",
+			changes: []change{
+				{branchID: "a", seq: 5, want: true},
+				{seq: 6, want: true},
+				{branchID: "a", seq: 5, want: false},
+				{branchID: "b", seq: 7, want: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequence := &StatusSequence{}
+			for i, c := range tt.changes {
+				if got := sequence.Accept(c.branchID, c.seq); got != c.want {
+					t.Fatalf("change %d: Accept(%q, %d) = %v, want %v", i, c.branchID, c.seq, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStatusSequenceAcceptRecordsApplied(t *testing.T) {
+	sequence := &StatusSequence{}
+	sequence.Accept("", 3)
+	sequence.Accept("a", 5)
+
+	if sequence.Applied != 3 {
+		t.Errorf("Applied = %d, want 3", sequence.Applied)
+	}
+	if sequence.AppliedMax != 5 {
+		t.Errorf("AppliedMax = %d, want 5", sequence.AppliedMax)
+	}
+	if len(sequence.Branches) != 1 || sequence.Branches[0] != (BranchSequence{BranchID: "a", Applied: 5}) {
+		t.Errorf("Branches = %+v, want [{a 5}]", sequence.Branches)
+	}
+
+	// rejected changes leave the sequence as it was
+	sequence.Accept("a", 4)
+	sequence.Accept("", 5)
+	if sequence.Applied != 3 || sequence.AppliedMax != 5 || sequence.Branches[0].Applied != 5 {
+		t.Errorf("sequence changed by rejected changes: %+v", sequence)
+	}
+}
+
+func TestStatusSequenceAppliedTo(t *testing.T) {
+	sequence := &StatusSequence{
+		Applied:    4,
+		AppliedMax: 7,
+		Branches: []BranchSequence{
+			{BranchID: "a", Applied: 7},
+			{BranchID: "b", Applied: 2},
+		},
+	}
+
+	tests := []struct {
+		branchID string
+		want     int64
+	}{
+		{branchID: "", want: 4},
+		{branchID: "a", want: 7},
+		{branchID: "b", want: 4},
+		{branchID: "c", want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := sequence.AppliedTo(tt.branchID); got != tt.want {
+			t.Errorf("AppliedTo(%q) = %d, want %d", tt.branchID, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// StopListEntry is a product of a restaurant's current menu that is not
+// available, along with its last status change.
+type StopListEntry struct {
+	ProductID string     `json:"product_id"`
+	Name      string     `json:"name"`
+	Category  string     `json:"category"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	UserID    string     `json:"user_id"`
+	ChangedAt *time.Time `json:"changed_at"`
+}
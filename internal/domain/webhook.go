@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEventTypes are the events a webhook can subscribe to.
+var WebhookEventTypes = []string{
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductStatusChanged,
+	EventProductDeleted,
+	EventAttributeStatusChanged,
+	EventMenuImported,
+}
+
+// Webhook is a subscription of a partner system to events of a restaurant.
+// An empty EventTypes subscribes to all events. Deliveries are signed with
+// Secret, which is never returned by the API.
+type Webhook struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RestaurantID string             `bson:"restaurant_id" json:"restaurant_id"`
+	URL          string             `bson:"url" json:"url"`
+	EventTypes   []string           `bson:"event_types" json:"event_types"`
+	Secret       string             `bson:"secret" json:"-"`
+	Active       bool               `bson:"active" json:"active"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+func (w *Webhook) Subscribed(eventType string) bool {
+	return w.Active && (len(w.EventTypes) == 0 || slices.Contains(w.EventTypes, eventType))
+}
+
+// WebhookEvent is the body of a webhook delivery. ID is shared by the
+// deliveries of the event to every subscribed webhook and by their retries.
+type WebhookEvent struct {
+	ID           string                 `json:"id"`
+	Type         string                 `json:"type"`
+	RestaurantID string                 `json:"restaurant_id"`
+	OccurredAt   time.Time              `json:"occurred_at"`
+	Data         map[string]interface{} `json:"data"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is an event queued for a webhook along with the log of its
+// delivery attempts. A pending delivery is attempted again at NextAttemptAt,
+// a failed one ran out of attempts.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WebhookID     primitive.ObjectID `bson:"webhook_id" json:"webhook_id"`
+	RestaurantID  string             `bson:"restaurant_id" json:"restaurant_id"`
+	EventID       string             `bson:"event_id" json:"event_id"`
+	EventType     string             `bson:"event_type" json:"event_type"`
+	Payload       json.RawMessage    `bson:"payload" json:"payload"`
+	State         string             `bson:"state" json:"state"`
+	Attempts      int                `bson:"attempts" json:"attempts"`
+	LastError     string             `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at" json:"next_attempt_at"`
+	Log           []WebhookAttempt   `bson:"log" json:"log"`
+	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
+	DeliveredAt   *time.Time         `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+// WebhookAttempt is one request made for a delivery. Retries go to the URL
+// the webhook has at the time. StatusCode is zero when no response was
+// received.
+type WebhookAttempt struct {
+	At         time.Time `bson:"at" json:"at"`
+	URL        string    `bson:"url,omitempty" json:"url,omitempty"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// WebhookDeliveryFilter narrows the delivery log of a webhook. Deliveries are
+// returned newest first, AfterTimestamp and AfterID are the position of the
+// last delivery of the previous page. Empty fields are not applied.
+type WebhookDeliveryFilter struct {
+	WebhookID      primitive.ObjectID
+	State          string
+	AfterTimestamp *time.Time
+	AfterID        primitive.ObjectID
+	Limit          int
+}
+
+// WebhookDeliveryPage is a page of the delivery log. NextCursor is empty on
+// the last page.
+type WebhookDeliveryPage struct {
+	Items      []WebhookDelivery `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
@@ -80,7 +80,7 @@ func (p *GoogleSheetsParser) ParseMenu(ctx context.Context, spreadsheetID, resta
 			product := domain.Product{
 				ID:       fmt.Sprintf("%v", row[0]),
 				Category: currentCategory,
-				Status:   "available",
+				Status:   domain.ProductStatusAvailable,
 			}
 
 			if len(row) > 1 {
@@ -143,7 +143,8 @@ func (p *GoogleSheetsParser) ParseMenu(ctx context.Context, spreadsheetID, resta
 
 				if _, exists := attributesMap[attrID]; !exists {
 					attr := &domain.Attribute{
-						ID: attrID,
+						ID:     attrID,
+						Status: domain.AttributeStatusAvailable,
 					}
 
 					if len(row) > 10 {
@@ -0,0 +1,14 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *domain.AuditLogEntry) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*domain.AuditLogEntry, error)
+	Find(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLogEntry, error)
+}
@@ -0,0 +1,22 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+// BranchRepository stores branches and their overrides. Set methods with a
+// nil price or an empty status remove that part of the override.
+type BranchRepository interface {
+	Create(ctx context.Context, branch *domain.Branch) error
+	GetByID(ctx context.Context, restaurantID, branchID string) (*domain.Branch, error)
+	ListByRestaurantID(ctx context.Context, restaurantID string) ([]domain.Branch, error)
+	Delete(ctx context.Context, restaurantID, branchID string) error
+	SetProductPrice(ctx context.Context, restaurantID, branchID, productID string, price *float64) error
+	SetProductStatus(ctx context.Context, restaurantID, branchID, productID, status string) error
+	SetAttributePrice(ctx context.Context, restaurantID, branchID, attributeID string, price *float64) error
+	SetAttributeStatus(ctx context.Context, restaurantID, branchID, attributeID, status string) error
+	ClearProductStatus(ctx context.Context, restaurantID, productID string) error
+	ClearAttributeStatus(ctx context.Context, restaurantID, attributeID string) error
+}
@@ -2,18 +2,42 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type MenuRepository interface {
 	Create(ctx context.Context, menu *domain.Menu) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*domain.Menu, error)
+	GetDocumentByID(ctx context.Context, id primitive.ObjectID, fields []string) (bson.M, error)
 	GetByRestaurantID(ctx context.Context, restaurantID string) (*domain.Menu, error)
 	Update(ctx context.Context, menu *domain.Menu) error
 	UpdateProductStatus(ctx context.Context, menuID primitive.ObjectID, productID string, status string) error
 	FindMenuByProductID(ctx context.Context, productID string) (*domain.Menu, error)
-	UpdateProductStatusByProductID(ctx context.Context, productID string, status string) error
+	FindRestaurantIDsByProductID(ctx context.Context, productID string) ([]string, error)
+	ReserveVersion(ctx context.Context, menuID primitive.ObjectID, version int64) error
+	AddProduct(ctx context.Context, menuID primitive.ObjectID, product *domain.Product) error
+	ReplaceProduct(ctx context.Context, menuID primitive.ObjectID, product *domain.Product) error
+	RemoveProduct(ctx context.Context, menuID primitive.ObjectID, productID string) error
+	ListProducts(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter) ([]domain.Product, error)
+	ListProductDocuments(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter, fields []string) ([]bson.M, error)
+	SearchProducts(ctx context.Context, filter domain.ProductFilter) ([]domain.ProductSearchResult, error)
+	SearchProductDocuments(ctx context.Context, filter domain.ProductFilter, fields []string) ([]bson.M, error)
+	LinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, productID, groupID string, version int64) error
+	UnlinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, productID, groupID string, version int64) error
+	AddAttributeGroup(ctx context.Context, menuID primitive.ObjectID, group *domain.AttributeGroup, version int64) error
+	ReplaceAttributeGroup(ctx context.Context, menuID primitive.ObjectID, group *domain.AttributeGroup, version int64) error
+	RemoveAttributeGroup(ctx context.Context, menuID primitive.ObjectID, groupID string, version int64) error
+	AddAttribute(ctx context.Context, menuID primitive.ObjectID, attribute *domain.Attribute, version int64) error
+	ReplaceAttribute(ctx context.Context, menuID primitive.ObjectID, attribute *domain.Attribute, version int64) error
+	RemoveAttribute(ctx context.Context, menuID primitive.ObjectID, attributeID string, version int64) error
+	UpdateAttributeStatus(ctx context.Context, menuID primitive.ObjectID, attributeID string, status string) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	SoftDelete(ctx context.Context, id primitive.ObjectID, version int64) error
+	Restore(ctx context.Context, id primitive.ObjectID) (*domain.Menu, error)
+	FindDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Menu, error)
+	Purge(ctx context.Context, id primitive.ObjectID, before time.Time) error
 }
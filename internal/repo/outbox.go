@@ -0,0 +1,16 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OutboxRepository interface {
+	Create(ctx context.Context, message *domain.OutboxMessage) error
+	ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*domain.OutboxMessage, error)
+	MarkSent(ctx context.Context, id primitive.ObjectID) error
+	MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error
+}
@@ -10,7 +10,8 @@ import (
 type ParsingTaskRepository interface {
 	Create(ctx context.Context, task *domain.ParsingTask) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*domain.ParsingTask, error)
+	StartProcessing(ctx context.Context, id primitive.ObjectID) error
 	UpdateStatus(ctx context.Context, id primitive.ObjectID, status domain.ParsingTaskStatus, errorMsg string) error
-	UpdateWithMenuID(ctx context.Context, id primitive.ObjectID, menuID primitive.ObjectID, status domain.ParsingTaskStatus) error
+	UpdateWithMenuID(ctx context.Context, id primitive.ObjectID, menuID primitive.ObjectID, status domain.ParsingTaskStatus, carriedStatuses []domain.CarriedStatus) error
 	IncrementRetryCount(ctx context.Context, id primitive.ObjectID) error
 }
@@ -0,0 +1,11 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+type ProcessedEventRepository interface {
+	Create(ctx context.Context, event *domain.ProcessedEvent) error
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 
 	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ProductStatusAuditRepository interface {
 	Create(ctx context.Context, audit *domain.ProductStatusAudit) error
-	GetByProductID(ctx context.Context, productID string, limit int) ([]domain.ProductStatusAudit, error)
+	GetByProductID(ctx context.Context, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error)
+	GetByRestaurantID(ctx context.Context, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error)
+	Stream(ctx context.Context, filter domain.ProductStatusAuditFilter, fn func(audit domain.ProductStatusAudit) error) error
+	DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error)
+	Upsert(ctx context.Context, audits []domain.ProductStatusAudit) error
+	GetLatestByProductIDs(ctx context.Context, restaurantID string, productIDs []string) (map[string]domain.ProductStatusAudit, error)
 }
@@ -0,0 +1,17 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ScheduledRestoreRepository interface {
+	Create(ctx context.Context, restore *domain.ScheduledRestore) error
+	CancelPending(ctx context.Context, restaurantID, branchID, productID string) error
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*domain.ScheduledRestore, error)
+	Complete(ctx context.Context, id primitive.ObjectID, state string) error
+	Release(ctx context.Context, id primitive.ObjectID, runAt time.Time) error
+}
@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+type StatusSequenceRepository interface {
+	Next(ctx context.Context, restaurantID, entityType, entityID string) (int64, error)
+	Get(ctx context.Context, restaurantID, entityType, entityID string) (*domain.StatusSequence, error)
+	SaveApplied(ctx context.Context, sequence *domain.StatusSequence) error
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *domain.Webhook) error
+	GetByID(ctx context.Context, restaurantID string, id primitive.ObjectID) (*domain.Webhook, error)
+	ListByRestaurantID(ctx context.Context, restaurantID string) ([]domain.Webhook, error)
+	ListSubscribed(ctx context.Context, restaurantID, eventType string) ([]domain.Webhook, error)
+	Update(ctx context.Context, webhook *domain.Webhook) error
+	Delete(ctx context.Context, restaurantID string, id primitive.ObjectID) error
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*domain.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, attempt domain.WebhookAttempt) error
+	Find(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error)
+}
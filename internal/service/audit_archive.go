@@ -0,0 +1,206 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// archiveBatchSize is the number of audit records deleted or restored per
+// query
+const archiveBatchSize = 1000
+
+// AuditArchiveService moves old product status audit records to
+// gzip-compressed NDJSON files and restores them back.
+type AuditArchiveService struct {
+	auditRepo repo.ProductStatusAuditRepository
+	dir       string
+	logger    *zap.SugaredLogger
+}
+
+func NewAuditArchiveService(
+	auditRepo repo.ProductStatusAuditRepository,
+	dir string,
+	logger *zap.SugaredLogger,
+) *AuditArchiveService {
+	return &AuditArchiveService{
+		auditRepo: auditRepo,
+		dir:       dir,
+		logger:    logger,
+	}
+}
+
+// ArchiveBefore moves audit records older than before to a new file in the
+// archive directory and returns its path and the number of records deleted.
+// Records are deleted only after the file is synced and read back with every
+// record in place. The path is empty when there was nothing to archive.
+func (s *AuditArchiveService) ArchiveBefore(ctx context.Context, before time.Time) (string, int, error) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	// the ID keeps names unique when several instances archive at once
+	name := fmt.Sprintf("product_status_audit-%s-%s.ndjson.gz", before.UTC().Format("20060102T150405Z"), primitive.NewObjectID().Hex())
+	path := filepath.Join(s.dir, name)
+	tmpPath := path + ".tmp"
+
+	ids, err := s.writeArchive(ctx, tmpPath, before)
+	if err == nil && len(ids) > 0 {
+		err = verifyArchive(tmpPath, ids)
+	}
+	if err != nil || len(ids) == 0 {
+		os.Remove(tmpPath)
+		return "", 0, err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", 0, fmt.Errorf("failed to move archive into place: %w", err)
+	}
+
+	s.logger.Infow("audit archive written", "path", path, "records", len(ids))
+
+	// a failed delete leaves records in both places, archiving them again
+	// later is harmless since restores upsert by ID
+	deleted := 0
+	for start := 0; start < len(ids); start += archiveBatchSize {
+		end := min(start+archiveBatchSize, len(ids))
+		count, err := s.auditRepo.DeleteByIDs(ctx, ids[start:end])
+		deleted += int(count)
+		if err != nil {
+			return path, deleted, err
+		}
+	}
+
+	return path, deleted, nil
+}
+
+// Restore loads the records of an archive file back into the collection and
+// returns how many were read. Records still in the collection are replaced,
+// so an archive can be restored more than once.
+func (s *AuditArchiveService) Restore(ctx context.Context, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read archive: %w", err)
+	}
+	defer reader.Close()
+
+	restored := 0
+	batch := make([]domain.ProductStatusAudit, 0, archiveBatchSize)
+	decoder := json.NewDecoder(reader)
+	for {
+		var audit domain.ProductStatusAudit
+		err := decoder.Decode(&audit)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return restored, fmt.Errorf("failed to decode record %d: %w", restored+len(batch)+1, err)
+		}
+
+		batch = append(batch, audit)
+		if len(batch) == archiveBatchSize {
+			if err := s.auditRepo.Upsert(ctx, batch); err != nil {
+				return restored, err
+			}
+			restored += len(batch)
+			batch = batch[:0]
+		}
+	}
+
+	if err := s.auditRepo.Upsert(ctx, batch); err != nil {
+		return restored, err
+	}
+	restored += len(batch)
+
+	s.logger.Infow("audit archive restored", "path", path, "records", restored)
+
+	return restored, nil
+}
+
+// writeArchive streams records older than before into a new file at path and
+// returns their IDs in the order written.
+func (s *AuditArchiveService) writeArchive(ctx context.Context, path string, before time.Time) ([]primitive.ObjectID, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	var ids []primitive.ObjectID
+	filter := domain.ProductStatusAuditFilter{To: &before}
+	err = s.auditRepo.Stream(ctx, filter, func(audit domain.ProductStatusAudit) error {
+		ids = append(ids, audit.ID)
+		return encoder.Encode(audit)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync archive: %w", err)
+	}
+
+	return ids, nil
+}
+
+// verifyArchive reads the archive back and checks it holds exactly the
+// records with the given IDs, in order.
+func verifyArchive(path string, ids []primitive.ObjectID) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive for verification: %w", err)
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to verify archive: %w", err)
+	}
+	defer reader.Close()
+
+	decoder := json.NewDecoder(reader)
+	for i, id := range ids {
+		var audit domain.ProductStatusAudit
+		if err := decoder.Decode(&audit); err != nil {
+			return fmt.Errorf("failed to verify archive: record %d: %w", i+1, err)
+		}
+		if audit.ID != id {
+			return fmt.Errorf("failed to verify archive: record %d is %s, expected %s", i+1, audit.ID.Hex(), id.Hex())
+		}
+	}
+
+	if decoder.More() {
+		return errors.New("failed to verify archive: unexpected records at the end")
+	}
+
+	// reading to EOF checks the gzip checksum
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("failed to verify archive: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// AuditLogService reads the audit log. Entries are written by the services
+// making the changes.
+type AuditLogService struct {
+	auditLogRepo repo.AuditLogRepository
+	logger       *zap.SugaredLogger
+}
+
+func NewAuditLogService(
+	auditLogRepo repo.AuditLogRepository,
+	logger *zap.SugaredLogger,
+) *AuditLogService {
+	return &AuditLogService{
+		auditLogRepo: auditLogRepo,
+		logger:       logger,
+	}
+}
+
+func (s *AuditLogService) GetEntry(ctx context.Context, id primitive.ObjectID) (*domain.AuditLogEntry, error) {
+	return s.auditLogRepo.GetByID(ctx, id)
+}
+
+// ListEntries returns a page of audit log entries matching the filter, newest
+// first. cursor is the NextCursor of the previous page, empty for the first
+// one.
+func (s *AuditLogService) ListEntries(ctx context.Context, filter domain.AuditLogFilter, cursor string) (*domain.AuditLogPage, error) {
+	if cursor != "" {
+		timestamp, id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterTimestamp = &timestamp
+		filter.AfterID = id
+	}
+
+	// one extra entry tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	entries, err := s.auditLogRepo.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	page := &domain.AuditLogPage{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeAuditCursor(last.Timestamp, last.ID)
+	}
+
+	return page, nil
+}
+
+// writeAuditLog stores the entry. Outside of a transaction it is called after
+// the change was made, so a failure is returned to the caller but does not
+// undo the change.
+func writeAuditLog(ctx context.Context, auditLogRepo repo.AuditLogRepository, logger *zap.SugaredLogger, entry *domain.AuditLogEntry) error {
+	if err := auditLogRepo.Create(ctx, entry); err != nil {
+		logger.Errorw("failed to create audit log entry", "action", entry.Action, "entity_type", entry.EntityType, "entity_id", entry.EntityID, "error", err)
+		return fmt.Errorf("failed to create audit log entry: %w", err)
+	}
+
+	return nil
+}
+
+// snapshot returns the stored representation of v for the before and after
+// fields of audit log entries, nil for nil pointers.
+func snapshot(v interface{}) bson.M {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+
+	return doc
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// BranchService manages branches of a restaurant and resolves the
+// restaurant's current menu for a branch. Branch status overrides are changed
+// through ProductService so they are applied and audited by the worker.
+type BranchService struct {
+	branchRepo   repo.BranchRepository
+	menuRepo     repo.MenuRepository
+	auditLogRepo repo.AuditLogRepository
+	storage      *mongo.Storage
+	logger       *zap.SugaredLogger
+}
+
+func NewBranchService(
+	branchRepo repo.BranchRepository,
+	menuRepo repo.MenuRepository,
+	auditLogRepo repo.AuditLogRepository,
+	storage *mongo.Storage,
+	logger *zap.SugaredLogger,
+) *BranchService {
+	return &BranchService{
+		branchRepo:   branchRepo,
+		menuRepo:     menuRepo,
+		auditLogRepo: auditLogRepo,
+		storage:      storage,
+		logger:       logger,
+	}
+}
+
+func (s *BranchService) CreateBranch(ctx context.Context, branch *domain.Branch, userID string) error {
+	err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.branchRepo.Create(ctx, branch); err != nil {
+			return err
+		}
+
+		entry := branchAuditEntry(branch.RestaurantID, branch.ID, domain.BranchActionCreated, userID)
+		entry.After = snapshot(branch)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("branch created", "restaurant_id", branch.RestaurantID, "branch_id", branch.ID)
+
+	return nil
+}
+
+func (s *BranchService) GetBranch(ctx context.Context, restaurantID, branchID string) (*domain.Branch, error) {
+	return s.branchRepo.GetByID(ctx, restaurantID, branchID)
+}
+
+func (s *BranchService) ListBranches(ctx context.Context, restaurantID string) ([]domain.Branch, error) {
+	return s.branchRepo.ListByRestaurantID(ctx, restaurantID)
+}
+
+func (s *BranchService) DeleteBranch(ctx context.Context, restaurantID, branchID, userID string) error {
+	branch, err := s.branchRepo.GetByID(ctx, restaurantID, branchID)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.branchRepo.Delete(ctx, restaurantID, branchID); err != nil {
+			return err
+		}
+
+		entry := branchAuditEntry(restaurantID, branchID, domain.BranchActionDeleted, userID)
+		entry.Before = snapshot(branch)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("branch deleted", "restaurant_id", restaurantID, "branch_id", branchID)
+
+	return nil
+}
+
+// SetProductPrice overrides the price of a product of the restaurant's
+// current menu in the branch. A nil price removes the override.
+func (s *BranchService) SetProductPrice(ctx context.Context, restaurantID, branchID, productID string, price *float64, userID string) error {
+	if price != nil && *price < 0 {
+		return fmt.Errorf("%w: price must not be negative", domain.ErrInvalidProduct)
+	}
+
+	if price != nil {
+		menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+		if err != nil {
+			return err
+		}
+		if findProduct(menu, productID) == nil {
+			return domain.ErrProductNotFound
+		}
+	}
+
+	branch, err := s.branchRepo.GetByID(ctx, restaurantID, branchID)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.branchRepo.SetProductPrice(ctx, restaurantID, branchID, productID, price); err != nil {
+			return err
+		}
+
+		var before *float64
+		if override := branch.ProductOverride(productID); override != nil {
+			before = override.Price
+		}
+		entry := branchAuditEntry(restaurantID, branchID, domain.BranchActionPriceChanged, userID)
+		entry.Before = bson.M{"product_id": productID, "price": before}
+		entry.After = bson.M{"product_id": productID, "price": price}
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("branch product price set", "restaurant_id", restaurantID, "branch_id", branchID, "product_id", productID, "price", price)
+
+	return nil
+}
+
+// SetAttributePrice overrides the price of an attribute of the restaurant's
+// current menu in the branch. A nil price removes the override.
+func (s *BranchService) SetAttributePrice(ctx context.Context, restaurantID, branchID, attributeID string, price *float64, userID string) error {
+	if price != nil && *price < 0 {
+		return fmt.Errorf("%w: price must not be negative", domain.ErrInvalidAttribute)
+	}
+
+	if price != nil {
+		menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+		if err != nil {
+			return err
+		}
+		if findAttribute(menu, attributeID) == nil {
+			return domain.ErrAttributeNotFound
+		}
+	}
+
+	branch, err := s.branchRepo.GetByID(ctx, restaurantID, branchID)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.branchRepo.SetAttributePrice(ctx, restaurantID, branchID, attributeID, price); err != nil {
+			return err
+		}
+
+		var before *float64
+		if override := branch.AttributeOverride(attributeID); override != nil {
+			before = override.Price
+		}
+		entry := branchAuditEntry(restaurantID, branchID, domain.BranchActionPriceChanged, userID)
+		entry.Before = bson.M{"attribute_id": attributeID, "price": before}
+		entry.After = bson.M{"attribute_id": attributeID, "price": price}
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("branch attribute price set", "restaurant_id", restaurantID, "branch_id", branchID, "attribute_id", attributeID, "price", price)
+
+	return nil
+}
+
+// ResolvedMenu returns the restaurant's current menu with the branch's
+// overrides applied.
+func (s *BranchService) ResolvedMenu(ctx context.Context, restaurantID, branchID string) (*domain.Menu, error) {
+	branch, err := s.branchRepo.GetByID(ctx, restaurantID, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveBranchMenu(menu, branch), nil
+}
+
+// Quote prices an order line against the menu resolved for the branch.
+func (s *BranchService) Quote(ctx context.Context, restaurantID, branchID string, req domain.QuoteRequest) (*domain.Quote, error) {
+	menu, err := s.ResolvedMenu(ctx, restaurantID, branchID)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateQuote(menu, req)
+}
+
+func branchAuditEntry(restaurantID, branchID, action, userID string) *domain.AuditLogEntry {
+	return &domain.AuditLogEntry{
+		Actor:        userID,
+		Action:       action,
+		EntityType:   domain.EntityBranch,
+		EntityID:     branchID,
+		RestaurantID: restaurantID,
+	}
+}
+
+// resolveBranchMenu returns a copy of the menu with the branch's price and
+// status overrides applied.
+func resolveBranchMenu(menu *domain.Menu, branch *domain.Branch) *domain.Menu {
+	resolved := *menu
+
+	resolved.Products = make([]domain.Product, len(menu.Products))
+	for i, product := range menu.Products {
+		if override := branch.ProductOverride(product.ID); override != nil {
+			if override.Price != nil {
+				product.Price = *override.Price
+			}
+			if override.Status != "" {
+				product.Status = override.Status
+			}
+		}
+		resolved.Products[i] = product
+	}
+
+	resolved.Attributes = make([]domain.Attribute, len(menu.Attributes))
+	for i, attribute := range menu.Attributes {
+		if override := branch.AttributeOverride(attribute.ID); override != nil {
+			if override.Price != nil {
+				attribute.Price = *override.Price
+			}
+			if override.Status != "" {
+				attribute.Status = override.Status
+			}
+		}
+		resolved.Attributes[i] = attribute
+	}
+
+	return &resolved
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+var (
+	menuFields           = jsonFields(domain.Menu{})
+	productFields        = jsonFields(domain.Product{})
+	attributeGroupFields = jsonFields(domain.AttributeGroup{})
+	attributeFields      = jsonFields(domain.Attribute{})
+
+	// nested fields of a menu that can be narrowed down further
+	menuSubFields = map[string]map[string]bool{
+		"products":          productFields,
+		"attributes_groups": attributeGroupFields,
+		"attributes":        attributeFields,
+	}
+)
+
+// menuProjection validates requested menu fields, such as "name" or
+// "products.status", and returns them as stored field paths.
+func menuProjection(fields []string) ([]string, error) {
+	var paths []string
+	for _, field := range fields {
+		parent, child, nested := strings.Cut(field, ".")
+		switch {
+		case !nested && menuFields[field]:
+		case nested && menuSubFields[parent][child]:
+		default:
+			return nil, fmt.Errorf("%w: unknown menu field %q", domain.ErrInvalidFields, field)
+		}
+
+		if field == "id" {
+			field = "_id"
+		}
+		paths = append(paths, field)
+	}
+
+	return normalizePaths(paths), nil
+}
+
+// productProjection validates requested product fields.
+func productProjection(fields []string) ([]string, error) {
+	for _, field := range fields {
+		if !productFields[field] {
+			return nil, fmt.Errorf("%w: unknown product field %q", domain.ErrInvalidFields, field)
+		}
+	}
+
+	return normalizePaths(fields), nil
+}
+
+// normalizePaths removes duplicates and paths already covered by a parent
+// path, MongoDB rejects projections with colliding paths.
+func normalizePaths(paths []string) []string {
+	sorted := append([]string(nil), paths...)
+	sort.Strings(sorted)
+
+	var result []string
+	for _, path := range sorted {
+		if len(result) > 0 {
+			last := result[len(result)-1]
+			if path == last || strings.HasPrefix(path, last+".") {
+				continue
+			}
+		}
+		result = append(result, path)
+	}
+
+	return result
+}
+
+func jsonFields(v interface{}) map[string]bool {
+	fields := make(map[string]bool)
+
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+
+	return fields
+}
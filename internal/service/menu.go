@@ -0,0 +1,648 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// systemUserID attributes changes made by background jobs
+	systemUserID = "system"
+
+	// purgeBatchSize is the number of deleted menus purged per query
+	purgeBatchSize = 100
+)
+
+type MenuService struct {
+	menuRepo     repo.MenuRepository
+	auditLogRepo repo.AuditLogRepository
+	storage      *mongo.Storage
+	logger       *zap.SugaredLogger
+}
+
+func NewMenuService(
+	menuRepo repo.MenuRepository,
+	auditLogRepo repo.AuditLogRepository,
+	storage *mongo.Storage,
+	logger *zap.SugaredLogger,
+) *MenuService {
+	return &MenuService{
+		menuRepo:     menuRepo,
+		auditLogRepo: auditLogRepo,
+		storage:      storage,
+		logger:       logger,
+	}
+}
+
+// DeleteMenu soft-deletes the menu, it can be restored until it is purged.
+func (s *MenuService) DeleteMenu(ctx context.Context, menuID primitive.ObjectID, version int64, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.SoftDelete(ctx, menuID, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.MenuActionDeleted, userID)
+		entry.Before = menuSummary(menu)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("menu deleted", "menu_id", menuID.Hex(), "restaurant_id", menu.RestaurantID, "user_id", userID)
+
+	return nil
+}
+
+func (s *MenuService) RestoreMenu(ctx context.Context, menuID primitive.ObjectID, userID string) (*domain.Menu, error) {
+	var menu *domain.Menu
+	err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		menu, err = s.menuRepo.Restore(ctx, menuID)
+		if err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.MenuActionRestored, userID)
+		entry.After = menuSummary(menu)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Infow("menu restored", "menu_id", menuID.Hex(), "restaurant_id", menu.RestaurantID, "user_id", userID)
+
+	return menu, nil
+}
+
+// PurgeDeletedMenus permanently removes menus soft-deleted before the given
+// time and returns how many were purged.
+func (s *MenuService) PurgeDeletedMenus(ctx context.Context, before time.Time) (int, error) {
+	purged := 0
+	for {
+		menus, err := s.menuRepo.FindDeletedBefore(ctx, before, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for i := range menus {
+			err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+				if err := s.menuRepo.Purge(ctx, menus[i].ID, before); err != nil {
+					return err
+				}
+
+				entry := menuAuditEntry(&menus[i], domain.MenuActionPurged, systemUserID)
+				entry.Before = menuSummary(&menus[i])
+				return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+			})
+			if err != nil {
+				// restored since it was found
+				if errors.Is(err, domain.ErrMenuNotFound) {
+					continue
+				}
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(menus) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// menuAuditEntry returns an audit log entry of a change of an entity of the
+// menu, the menu itself by default.
+func menuAuditEntry(menu *domain.Menu, action, userID string) *domain.AuditLogEntry {
+	return &domain.AuditLogEntry{
+		Actor:        userID,
+		Action:       action,
+		EntityType:   domain.EntityMenu,
+		EntityID:     menu.ID.Hex(),
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menu.ID,
+	}
+}
+
+// menuSummary is the snapshot of a menu in the audit log. Whole menus are too
+// large to be copied into every entry, so only their counts are kept.
+func menuSummary(menu *domain.Menu) bson.M {
+	return bson.M{
+		"name":             menu.Name,
+		"restaurant_id":    menu.RestaurantID,
+		"version":          menu.Version,
+		"products":         len(menu.Products),
+		"attribute_groups": len(menu.AttributeGroups),
+		"attributes":       len(menu.Attributes),
+	}
+}
+
+// GetMenuFields returns the menu limited to the requested fields along with
+// its current version.
+func (s *MenuService) GetMenuFields(ctx context.Context, menuID primitive.ObjectID, fields []string) (bson.M, int64, error) {
+	paths, err := menuProjection(fields)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	menu, err := s.menuRepo.GetDocumentByID(ctx, menuID, paths)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// menus versioned by $inc alone store the version as int32
+	var version int64
+	switch v := menu["version"].(type) {
+	case int64:
+		version = v
+	case int32:
+		version = int64(v)
+	}
+	if !contains(paths, "version") {
+		delete(menu, "version")
+	}
+	if id, ok := menu["_id"]; ok {
+		menu["id"] = id
+		delete(menu, "_id")
+	}
+
+	return menu, version, nil
+}
+
+func (s *MenuService) ListProducts(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter) ([]domain.Product, error) {
+	if _, _, err := s.GetMenuFields(ctx, menuID, []string{"id"}); err != nil {
+		return nil, err
+	}
+
+	products, err := s.menuRepo.ListProducts(ctx, menuID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	return products, nil
+}
+
+func (s *MenuService) ListProductFields(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter, fields []string) ([]bson.M, error) {
+	paths, err := productProjection(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := s.GetMenuFields(ctx, menuID, []string{"id"}); err != nil {
+		return nil, err
+	}
+
+	products, err := s.menuRepo.ListProductDocuments(ctx, menuID, filter, paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	return products, nil
+}
+
+func (s *MenuService) SearchProductFields(ctx context.Context, filter domain.ProductFilter, fields []string) ([]bson.M, error) {
+	paths, err := productProjection(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.menuRepo.SearchProductDocuments(ctx, filter, paths)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	return results, nil
+}
+
+func (s *MenuService) SearchProducts(ctx context.Context, filter domain.ProductFilter) ([]domain.ProductSearchResult, error) {
+	results, err := s.menuRepo.SearchProducts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	return results, nil
+}
+
+func (s *MenuService) CreateAttributeGroup(ctx context.Context, menuID primitive.ObjectID, version int64, group domain.AttributeGroup, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	if findAttributeGroup(menu, group.ID) != nil {
+		return domain.ErrAttributeGroupExists
+	}
+
+	if err := validateAttributeGroup(menu, &group); err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.AddAttributeGroup(ctx, menuID, &group, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeGroupActionCreated, userID)
+		entry.EntityType = domain.EntityAttributeGroup
+		entry.EntityID = group.ID
+		entry.After = snapshot(group)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute group created", "menu_id", menuID.Hex(), "group_id", group.ID)
+
+	return nil
+}
+
+func (s *MenuService) UpdateAttributeGroup(ctx context.Context, menuID primitive.ObjectID, version int64, group domain.AttributeGroup, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	current := findAttributeGroup(menu, group.ID)
+	if current == nil {
+		return domain.ErrAttributeGroupNotFound
+	}
+
+	if err := validateAttributeGroup(menu, &group); err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.ReplaceAttributeGroup(ctx, menuID, &group, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeGroupActionUpdated, userID)
+		entry.EntityType = domain.EntityAttributeGroup
+		entry.EntityID = group.ID
+		entry.Before = snapshot(current)
+		entry.After = snapshot(group)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute group updated", "menu_id", menuID.Hex(), "group_id", group.ID)
+
+	return nil
+}
+
+func (s *MenuService) DeleteAttributeGroup(ctx context.Context, menuID primitive.ObjectID, version int64, groupID, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	current := findAttributeGroup(menu, groupID)
+	if current == nil {
+		return domain.ErrAttributeGroupNotFound
+	}
+
+	for _, product := range menu.Products {
+		if contains(product.Attributes, groupID) {
+			return fmt.Errorf("%w: product %q", domain.ErrAttributeGroupInUse, product.ID)
+		}
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.RemoveAttributeGroup(ctx, menuID, groupID, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeGroupActionDeleted, userID)
+		entry.EntityType = domain.EntityAttributeGroup
+		entry.EntityID = groupID
+		entry.Before = snapshot(current)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute group deleted", "menu_id", menuID.Hex(), "group_id", groupID)
+
+	return nil
+}
+
+func (s *MenuService) LinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, version int64, productID, groupID, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	product := findProduct(menu, productID)
+	if product == nil {
+		return domain.ErrProductNotFound
+	}
+
+	if findAttributeGroup(menu, groupID) == nil {
+		return domain.ErrAttributeGroupNotFound
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.LinkAttributeGroup(ctx, menuID, productID, groupID, version); err != nil {
+			return err
+		}
+
+		linked := product.Attributes
+		if !contains(linked, groupID) {
+			linked = append(append([]string{}, linked...), groupID)
+		}
+		entry := menuAuditEntry(menu, domain.ProductActionGroupLinked, userID)
+		entry.EntityType = domain.EntityProduct
+		entry.EntityID = productID
+		entry.Before = bson.M{"attributes": product.Attributes}
+		entry.After = bson.M{"attributes": linked}
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute group linked", "menu_id", menuID.Hex(), "product_id", productID, "group_id", groupID)
+
+	return nil
+}
+
+func (s *MenuService) UnlinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, version int64, productID, groupID, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	product := findProduct(menu, productID)
+	if product == nil {
+		return domain.ErrProductNotFound
+	}
+
+	if !contains(product.Attributes, groupID) {
+		return domain.ErrAttributeGroupNotFound
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.UnlinkAttributeGroup(ctx, menuID, productID, groupID, version); err != nil {
+			return err
+		}
+
+		unlinked := []string{}
+		for _, id := range product.Attributes {
+			if id != groupID {
+				unlinked = append(unlinked, id)
+			}
+		}
+		entry := menuAuditEntry(menu, domain.ProductActionGroupUnlinked, userID)
+		entry.EntityType = domain.EntityProduct
+		entry.EntityID = productID
+		entry.Before = bson.M{"attributes": product.Attributes}
+		entry.After = bson.M{"attributes": unlinked}
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute group unlinked", "menu_id", menuID.Hex(), "product_id", productID, "group_id", groupID)
+
+	return nil
+}
+
+// CreateAttribute adds an available attribute to the menu. Its status is
+// changed through ProductService.UpdateAttributeStatus afterwards.
+func (s *MenuService) CreateAttribute(ctx context.Context, menuID primitive.ObjectID, version int64, attribute *domain.Attribute, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	if findAttribute(menu, attribute.ID) != nil {
+		return domain.ErrAttributeExists
+	}
+
+	if err := validateAttribute(attribute); err != nil {
+		return err
+	}
+
+	attribute.Status = domain.AttributeStatusAvailable
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.AddAttribute(ctx, menuID, attribute, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeActionCreated, userID)
+		entry.EntityType = domain.EntityAttribute
+		entry.EntityID = attribute.ID
+		entry.After = snapshot(attribute)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute created", "menu_id", menuID.Hex(), "attribute_id", attribute.ID)
+
+	return nil
+}
+
+// UpdateAttribute replaces the attribute and keeps its current status.
+func (s *MenuService) UpdateAttribute(ctx context.Context, menuID primitive.ObjectID, version int64, attribute *domain.Attribute, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	current := findAttribute(menu, attribute.ID)
+	if current == nil {
+		return domain.ErrAttributeNotFound
+	}
+
+	if err := validateAttribute(attribute); err != nil {
+		return err
+	}
+
+	attribute.Status = current.Status
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.ReplaceAttribute(ctx, menuID, attribute, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeActionUpdated, userID)
+		entry.EntityType = domain.EntityAttribute
+		entry.EntityID = attribute.ID
+		entry.Before = snapshot(current)
+		entry.After = snapshot(attribute)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute updated", "menu_id", menuID.Hex(), "attribute_id", attribute.ID)
+
+	return nil
+}
+
+func (s *MenuService) DeleteAttribute(ctx context.Context, menuID primitive.ObjectID, version int64, attributeID, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	current := findAttribute(menu, attributeID)
+	if current == nil {
+		return domain.ErrAttributeNotFound
+	}
+
+	for _, group := range menu.AttributeGroups {
+		if contains(group.Attributes, attributeID) {
+			return fmt.Errorf("%w: group %q", domain.ErrAttributeInUse, group.ID)
+		}
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.RemoveAttribute(ctx, menuID, attributeID, version); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.AttributeActionDeleted, userID)
+		entry.EntityType = domain.EntityAttribute
+		entry.EntityID = attributeID
+		entry.Before = snapshot(current)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("attribute deleted", "menu_id", menuID.Hex(), "attribute_id", attributeID)
+
+	return nil
+}
+
+func findAttributeGroup(menu *domain.Menu, groupID string) *domain.AttributeGroup {
+	for i := range menu.AttributeGroups {
+		if menu.AttributeGroups[i].ID == groupID {
+			return &menu.AttributeGroups[i]
+		}
+	}
+	return nil
+}
+
+func findAttribute(menu *domain.Menu, attributeID string) *domain.Attribute {
+	for i := range menu.Attributes {
+		if menu.Attributes[i].ID == attributeID {
+			return &menu.Attributes[i]
+		}
+	}
+	return nil
+}
+
+// validateAttributeGroup checks group bounds and that every attribute it
+// references exists in the menu. A zero Max means no upper bound.
+func validateAttributeGroup(menu *domain.Menu, group *domain.AttributeGroup) error {
+	if group.Min < 0 || group.Max < 0 {
+		return fmt.Errorf("%w: min and max must not be negative", domain.ErrInvalidAttributeGroup)
+	}
+	if group.Max > 0 && group.Min > group.Max {
+		return fmt.Errorf("%w: min is greater than max", domain.ErrInvalidAttributeGroup)
+	}
+
+	seen := make(map[string]bool, len(group.Attributes))
+	for _, attributeID := range group.Attributes {
+		if findAttribute(menu, attributeID) == nil {
+			return fmt.Errorf("%w: unknown attribute %q", domain.ErrInvalidAttributeGroup, attributeID)
+		}
+		if seen[attributeID] {
+			return fmt.Errorf("%w: duplicate attribute %q", domain.ErrInvalidAttributeGroup, attributeID)
+		}
+		seen[attributeID] = true
+	}
+
+	if group.Attributes == nil {
+		group.Attributes = []string{}
+	}
+
+	return nil
+}
+
+// validateAttribute checks attribute bounds. A zero Max means no upper bound.
+func validateAttribute(attribute *domain.Attribute) error {
+	if attribute.Min < 0 || attribute.Max < 0 {
+		return fmt.Errorf("%w: min and max must not be negative", domain.ErrInvalidAttribute)
+	}
+	if attribute.Max > 0 && attribute.Min > attribute.Max {
+		return fmt.Errorf("%w: min is greater than max", domain.ErrInvalidAttribute)
+	}
+	if attribute.Price < 0 {
+		return fmt.Errorf("%w: price must not be negative", domain.ErrInvalidAttribute)
+	}
+
+	return nil
+}
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
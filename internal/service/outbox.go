@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/queue"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"go.uber.org/zap"
+)
+
+const (
+	// outboxLease is how long a claimed message is held by one relay, and so
+	// the delay before a failed publish is retried
+	outboxLease = 30 * time.Second
+
+	// outboxBatchSize is the most messages published per relay run
+	outboxBatchSize = 100
+)
+
+// OutboxService publishes messages stored in the outbox to the broker.
+// Messages are marked sent only after the broker accepted them, so each is
+// delivered at least once and consumers must tolerate duplicates.
+type OutboxService struct {
+	outboxRepo repo.OutboxRepository
+	broker     queue.Broker
+	logger     *zap.SugaredLogger
+}
+
+func NewOutboxService(
+	outboxRepo repo.OutboxRepository,
+	broker queue.Broker,
+	logger *zap.SugaredLogger,
+) *OutboxService {
+	return &OutboxService{
+		outboxRepo: outboxRepo,
+		broker:     broker,
+		logger:     logger,
+	}
+}
+
+// RelayPending publishes pending messages oldest first and returns how many
+// were published. It stops at the first failed publish, the broker is most
+// likely down and the rest would fail too.
+func (s *OutboxService) RelayPending(ctx context.Context, now time.Time) (int, error) {
+	sent := 0
+	for sent < outboxBatchSize {
+		message, err := s.outboxRepo.ClaimNext(ctx, now, outboxLease)
+		if err != nil {
+			return sent, err
+		}
+		if message == nil {
+			return sent, nil
+		}
+
+		if err := s.broker.Publish(ctx, message.Queue, message.Payload); err != nil {
+			if markErr := s.outboxRepo.MarkFailed(context.WithoutCancel(ctx), message.ID, err.Error()); markErr != nil {
+				s.logger.Errorw("failed to mark outbox message failed", "message_id", message.ID.Hex(), "error", markErr)
+			}
+			return sent, fmt.Errorf("failed to publish outbox message %s: %w", message.ID.Hex(), err)
+		}
+
+		// a failure here publishes the message again after the lease
+		if err := s.outboxRepo.MarkSent(ctx, message.ID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// enqueue stores a message for queueName in the outbox. Called with a
+// transaction context, the message is published only if the transaction
+// commits.
+func enqueue(ctx context.Context, outboxRepo repo.OutboxRepository, queueName string, v interface{}) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	message := &domain.OutboxMessage{
+		Queue:   queueName,
+		Payload: payload,
+	}
+
+	return outboxRepo.Create(ctx, message)
+}
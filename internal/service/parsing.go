@@ -2,14 +2,16 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/Beka01247/kwaaka-tz/internal/domain"
 	"github.com/Beka01247/kwaaka-tz/internal/parser"
 	"github.com/Beka01247/kwaaka-tz/internal/queue"
 	"github.com/Beka01247/kwaaka-tz/internal/repo"
 	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
@@ -17,8 +19,11 @@ import (
 type ParsingService struct {
 	parsingTaskRepo repo.ParsingTaskRepository
 	menuRepo        repo.MenuRepository
+	auditLogRepo    repo.AuditLogRepository
+	outboxRepo      repo.OutboxRepository
+	webhookRepo     repo.WebhookRepository
+	deliveryRepo    repo.WebhookDeliveryRepository
 	parser          *parser.GoogleSheetsParser
-	broker          queue.Broker
 	storage         *mongo.Storage
 	logger          *zap.SugaredLogger
 }
@@ -26,50 +31,62 @@ type ParsingService struct {
 func NewParsingService(
 	parsingTaskRepo repo.ParsingTaskRepository,
 	menuRepo repo.MenuRepository,
+	auditLogRepo repo.AuditLogRepository,
+	outboxRepo repo.OutboxRepository,
+	webhookRepo repo.WebhookRepository,
+	deliveryRepo repo.WebhookDeliveryRepository,
 	parser *parser.GoogleSheetsParser,
-	broker queue.Broker,
 	storage *mongo.Storage,
 	logger *zap.SugaredLogger,
 ) *ParsingService {
 	return &ParsingService{
 		parsingTaskRepo: parsingTaskRepo,
 		menuRepo:        menuRepo,
+		auditLogRepo:    auditLogRepo,
+		outboxRepo:      outboxRepo,
+		webhookRepo:     webhookRepo,
+		deliveryRepo:    deliveryRepo,
 		parser:          parser,
-		broker:          broker,
 		storage:         storage,
 		logger:          logger,
 	}
 }
 
-func (s *ParsingService) CreateParsingTask(ctx context.Context, spreadsheetID, restaurantName string) (primitive.ObjectID, error) {
-	// create parsing task
+// CreateParsingTask stores a queued task together with its message in the
+// outbox, so the task is never left queued without a message to process it.
+func (s *ParsingService) CreateParsingTask(ctx context.Context, spreadsheetID, restaurantName string, resetStatuses bool, userID string) (primitive.ObjectID, error) {
 	task := &domain.ParsingTask{
+		ID:             primitive.NewObjectID(),
 		Status:         domain.StatusQueued,
 		SpreadsheetID:  spreadsheetID,
 		RestaurantName: restaurantName,
 		RetryCount:     0,
+		ResetStatuses:  resetStatuses,
+		UserID:         userID,
 	}
 
-	if err := s.parsingTaskRepo.Create(ctx, task); err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to create parsing task: %w", err)
-	}
-
-	// publish message to queue
 	message := domain.MenuParsingMessage{
 		TaskID:         task.ID.Hex(),
 		SpreadsheetID:  spreadsheetID,
 		RestaurantName: restaurantName,
 	}
 
-	messageBytes, err := json.Marshal(message)
-	if err != nil {
-		return primitive.NilObjectID, fmt.Errorf("failed to marshal message: %w", err)
-	}
+	err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.parsingTaskRepo.Create(ctx, task); err != nil {
+			return fmt.Errorf("failed to create parsing task: %w", err)
+		}
+
+		entry := taskAuditEntry(task, domain.ParsingTaskActionCreated, userID)
+		entry.After = snapshot(task)
+		if err := writeAuditLog(ctx, s.auditLogRepo, s.logger, entry); err != nil {
+			return err
+		}
 
-	if err := s.broker.Publish(ctx, queue.QueueMenuParsing, messageBytes); err != nil {
-		// update task status to failed
-		_ = s.parsingTaskRepo.UpdateStatus(ctx, task.ID, domain.StatusFailed, err.Error())
-		return primitive.NilObjectID, fmt.Errorf("failed to publish message: %w", err)
+		return enqueue(ctx, s.outboxRepo, queue.QueueMenuParsing, message)
+	})
+	if err != nil {
+		s.logger.Errorw("failed to create parsing task", "spreadsheet_id", spreadsheetID, "error", err)
+		return primitive.NilObjectID, err
 	}
 
 	s.logger.Infow("parsing task created", "task_id", task.ID.Hex(), "spreadsheet_id", spreadsheetID)
@@ -93,8 +110,34 @@ func (s *ParsingService) ProcessParsingTask(ctx context.Context, taskID primitiv
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
+	// the message of a completed task is redelivered by the queue
+	if task.Status == domain.StatusCompleted {
+		s.logger.Infow("skipping completed parsing task", "task_id", taskID.Hex())
+		return nil
+	}
+
 	// update status to processing
-	if err := s.parsingTaskRepo.UpdateStatus(ctx, taskID, domain.StatusProcessing, ""); err != nil {
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.parsingTaskRepo.StartProcessing(ctx, taskID); err != nil {
+			return err
+		}
+
+		if task.Status == domain.StatusQueued {
+			return nil
+		}
+
+		// the task failed or its worker died, the queue delivers it again
+
+		entry := taskAuditEntry(task, domain.ParsingTaskActionRetried, systemUserID)
+		entry.Before = bson.M{"status": task.Status, "error_message": task.ErrorMessage}
+		entry.After = bson.M{"status": domain.StatusProcessing}
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if errors.Is(err, domain.ErrTaskAlreadyCompleted) {
+		s.logger.Infow("skipping completed parsing task", "task_id", taskID.Hex())
+		return nil
+	}
+	if err != nil {
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
 
@@ -108,45 +151,134 @@ func (s *ParsingService) ProcessParsingTask(ctx context.Context, taskID primitiv
 		return fmt.Errorf("failed to parse menu: %w", err)
 	}
 
-	// Use transaction to save menu and update task atomically
-	session, err := s.storage.StartSession()
-	if err != nil {
-		s.logger.Errorw("failed to start session", "task_id", taskID.Hex(), "error", err)
-		_ = s.parsingTaskRepo.UpdateStatus(ctx, taskID, domain.StatusFailed, "failed to start transaction")
-		return fmt.Errorf("failed to start session: %w", err)
+	// tasks created before they recorded a user were started by the system
+	userID := task.UserID
+	if userID == "" {
+		userID = systemUserID
 	}
-	defer session.EndSession(ctx)
 
-	err = session.StartTransaction()
-	if err != nil {
-		s.logger.Errorw("failed to start transaction", "task_id", taskID.Hex(), "error", err)
-		_ = s.parsingTaskRepo.UpdateStatus(ctx, taskID, domain.StatusFailed, "failed to start transaction")
-		return fmt.Errorf("failed to start transaction: %w", err)
+	// the transaction may run more than once, every run carries statuses onto
+	// the menu as it was parsed
+	parsedStatuses := make([]string, len(menu.Products))
+	for i, product := range menu.Products {
+		parsedStatuses[i] = product.Status
 	}
 
-	// save menu to database
-	if err := s.menuRepo.Create(ctx, menu); err != nil {
+	// carry the statuses, save the menu, complete the task, record the import
+	// and notify webhooks atomically
+	var carried []domain.CarriedStatus
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		for i := range menu.Products {
+			menu.Products[i].Status = parsedStatuses[i]
+		}
+
+		// keep stop-list changes made on the previous menu unless asked to
+		// reset them
+		carried = nil
+		if !task.ResetStatuses {
+			var err error
+			carried, err = s.carryProductStatuses(ctx, menu)
+			if err != nil {
+				return fmt.Errorf("failed to carry product statuses: %w", err)
+			}
+		}
+
+		if err := s.menuRepo.Create(ctx, menu); err != nil {
+			return fmt.Errorf("failed to save menu: %w", err)
+		}
+
+		if err := s.parsingTaskRepo.UpdateWithMenuID(ctx, taskID, menu.ID, domain.StatusCompleted, carried); err != nil {
+			return err
+		}
+
+		entry := menuAuditEntry(menu, domain.MenuActionImported, userID)
+		entry.After = menuSummary(menu)
+		entry.After["task_id"] = taskID.Hex()
+		entry.After["carried_statuses"] = len(carried)
+		if err := writeAuditLog(ctx, s.auditLogRepo, s.logger, entry); err != nil {
+			return err
+		}
+
+		event := domain.WebhookEvent{
+			Type:         domain.EventMenuImported,
+			RestaurantID: menu.RestaurantID,
+			OccurredAt:   time.Now(),
+			Data: map[string]interface{}{
+				"menu_id":          menu.ID.Hex(),
+				"name":             menu.Name,
+				"task_id":          taskID.Hex(),
+				"products":         len(menu.Products),
+				"carried_statuses": len(carried),
+				"user_id":          userID,
+			},
+		}
+		return queueWebhookEvent(ctx, s.webhookRepo, s.deliveryRepo, event)
+	})
+	// a concurrent delivery completed the task first, its menu is kept and
+	// this one is rolled back
+	if errors.Is(err, domain.ErrTaskAlreadyCompleted) {
+		s.logger.Infow("parsing task was completed by another delivery", "task_id", taskID.Hex())
+		return nil
+	}
+	if err != nil {
 		s.logger.Errorw("failed to save menu", "task_id", taskID.Hex(), "error", err)
-		session.AbortTransaction(ctx)
 		_ = s.parsingTaskRepo.UpdateStatus(ctx, taskID, domain.StatusFailed, err.Error())
-		return fmt.Errorf("failed to save menu: %w", err)
+		return err
 	}
 
-	// update task with menu ID and status
-	if err := s.parsingTaskRepo.UpdateWithMenuID(ctx, taskID, menu.ID, domain.StatusCompleted); err != nil {
-		s.logger.Errorw("failed to update task", "task_id", taskID.Hex(), "error", err)
-		session.AbortTransaction(ctx)
-		return fmt.Errorf("failed to update task: %w", err)
+	s.logger.Infow("parsing task completed", "task_id", taskID.Hex(), "menu_id", menu.ID.Hex(), "carried_statuses", len(carried))
+
+	return nil
+}
+
+func taskAuditEntry(task *domain.ParsingTask, action, userID string) *domain.AuditLogEntry {
+	return &domain.AuditLogEntry{
+		Actor:      userID,
+		Action:     action,
+		EntityType: domain.EntityParsingTask,
+		EntityID:   task.ID.Hex(),
 	}
+}
 
-	// commit transaction
-	if err := session.CommitTransaction(ctx); err != nil {
-		s.logger.Errorw("failed to commit transaction", "task_id", taskID.Hex(), "error", err)
-		_ = s.parsingTaskRepo.UpdateStatus(ctx, taskID, domain.StatusFailed, "failed to commit transaction")
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// carryProductStatuses copies non-default statuses of products present in the
+// restaurant's previous menu onto the freshly parsed one. It must run in the
+// transaction that saves the new menu.
+func (s *ParsingService) carryProductStatuses(ctx context.Context, menu *domain.Menu) ([]domain.CarriedStatus, error) {
+	prev, err := s.menuRepo.GetByRestaurantID(ctx, menu.RestaurantID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMenuNotFound) {
+			return nil, nil
+		}
+		return nil, err
 	}
 
-	s.logger.Infow("parsing task completed", "task_id", taskID.Hex(), "menu_id", menu.ID.Hex())
+	// every status change bumps the menu version, writing it here makes a
+	// change committed after the read conflict with the transaction, which
+	// then runs again and carries the change too
+	if err := s.menuRepo.ReserveVersion(ctx, prev.ID, prev.Version); err != nil {
+		return nil, err
+	}
 
-	return nil
+	prevStatuses := make(map[string]string, len(prev.Products))
+	for _, product := range prev.Products {
+		if product.Status != "" && product.Status != domain.ProductStatusAvailable {
+			prevStatuses[product.ID] = product.Status
+		}
+	}
+
+	var carried []domain.CarriedStatus
+	for i := range menu.Products {
+		status, ok := prevStatuses[menu.Products[i].ID]
+		if !ok {
+			continue
+		}
+
+		menu.Products[i].Status = status
+		carried = append(carried, domain.CarriedStatus{
+			ProductID: menu.Products[i].ID,
+			Status:    status,
+		})
+	}
+
+	return carried, nil
 }
@@ -2,143 +2,1262 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Beka01247/kwaaka-tz/internal/domain"
 	"github.com/Beka01247/kwaaka-tz/internal/queue"
 	"github.com/Beka01247/kwaaka-tz/internal/repo"
 	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
+// ProductSelector picks products for a batch status change: either explicit
+// product IDs or products of a restaurant's current menu by category or by
+// attribute.
+type ProductSelector struct {
+	ProductIDs   []string
+	RestaurantID string
+	Category     string
+	AttributeID  string
+}
+
+const (
+	BatchResultQueued    = "queued"
+	BatchResultUnchanged = "unchanged"
+	BatchResultNotFound  = "not_found"
+	BatchResultAmbiguous = "ambiguous"
+)
+
+type BatchStatusResult struct {
+	ProductID    string `json:"product_id"`
+	RestaurantID string `json:"restaurant_id,omitempty"`
+	MenuID       string `json:"menu_id,omitempty"`
+	OldStatus    string `json:"old_status,omitempty"`
+	Result       string `json:"result"`
+}
+
 type ProductService struct {
-	menuRepo  repo.MenuRepository
-	auditRepo repo.ProductStatusAuditRepository
-	broker    queue.Broker
-	storage   *mongo.Storage
-	logger    *zap.SugaredLogger
+	menuRepo     repo.MenuRepository
+	auditRepo    repo.ProductStatusAuditRepository
+	auditLogRepo repo.AuditLogRepository
+	restoreRepo  repo.ScheduledRestoreRepository
+	branchRepo   repo.BranchRepository
+	outboxRepo   repo.OutboxRepository
+	eventRepo    repo.ProcessedEventRepository
+	sequenceRepo repo.StatusSequenceRepository
+	webhookRepo  repo.WebhookRepository
+	deliveryRepo repo.WebhookDeliveryRepository
+	storage      *mongo.Storage
+	logger       *zap.SugaredLogger
 }
 
 func NewProductService(
 	menuRepo repo.MenuRepository,
 	auditRepo repo.ProductStatusAuditRepository,
-	broker queue.Broker,
+	auditLogRepo repo.AuditLogRepository,
+	restoreRepo repo.ScheduledRestoreRepository,
+	branchRepo repo.BranchRepository,
+	outboxRepo repo.OutboxRepository,
+	eventRepo repo.ProcessedEventRepository,
+	sequenceRepo repo.StatusSequenceRepository,
+	webhookRepo repo.WebhookRepository,
+	deliveryRepo repo.WebhookDeliveryRepository,
 	storage *mongo.Storage,
 	logger *zap.SugaredLogger,
 ) *ProductService {
 	return &ProductService{
-		menuRepo:  menuRepo,
-		auditRepo: auditRepo,
-		broker:    broker,
-		storage:   storage,
-		logger:    logger,
+		menuRepo:     menuRepo,
+		auditRepo:    auditRepo,
+		auditLogRepo: auditLogRepo,
+		restoreRepo:  restoreRepo,
+		branchRepo:   branchRepo,
+		outboxRepo:   outboxRepo,
+		eventRepo:    eventRepo,
+		sequenceRepo: sequenceRepo,
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		storage:      storage,
+		logger:       logger,
 	}
 }
 
-func (s *ProductService) UpdateProductStatus(ctx context.Context, productID, newStatus, reason, userID string) error {
+// UpdateProductStatus changes the status of a product in the current menu of
+// a restaurant and all its branches. With a branchID only the status in that
+// branch is overridden. Without a restaurantID the product ID must belong to a
+// single restaurant. With until set the previous status is restored at that
+// time.
+func (s *ProductService) UpdateProductStatus(ctx context.Context, restaurantID, branchID, productID, newStatus, reason, userID string, until *time.Time) error {
 	// find menu containing this product to get current status
-	menu, err := s.menuRepo.FindMenuByProductID(ctx, productID)
+	menu, err := s.findProductMenu(ctx, restaurantID, productID)
 	if err != nil {
 		return fmt.Errorf("failed to find product: %w", err)
 	}
 
-	// find product to get current status
-	var oldStatus string
-	found := false
+	oldStatus, err := s.productStatus(ctx, menu, branchID, productID)
+	if err != nil {
+		return err
+	}
+
+	// queue status change event (worker will update DB)
+	event := domain.ProductStatusEvent{
+		EventType:    domain.EventProductStatusChanged,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menu.ID.Hex(),
+		ProductID:    productID,
+		BranchID:     branchID,
+		OldStatus:    oldStatus,
+		NewStatus:    newStatus,
+		Reason:       reason,
+		UserID:       userID,
+	}
+
+	scheduled := until != nil && oldStatus != newStatus
+
+	// the event and the restores it replaces are stored together
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		sequence, err := s.sequenceRepo.Next(ctx, menu.RestaurantID, domain.EntityProduct, productID)
+		if err != nil {
+			return err
+		}
+		event.Sequence = sequence
+
+		if err := s.publishEvent(ctx, event); err != nil {
+			return err
+		}
+
+		// a manual change supersedes restores scheduled before it
+		if err := s.restoreRepo.CancelPending(ctx, menu.RestaurantID, branchID, productID); err != nil {
+			return err
+		}
+
+		if !scheduled {
+			return nil
+		}
+
+		restore := &domain.ScheduledRestore{
+			RestaurantID:  menu.RestaurantID,
+			BranchID:      branchID,
+			ProductID:     productID,
+			Status:        newStatus,
+			RestoreStatus: oldStatus,
+			Sequence:      sequence,
+			RunAt:         *until,
+			UserID:        userID,
+		}
+		return s.restoreRepo.Create(ctx, restore)
+	})
+	if err != nil {
+		s.logger.Errorw("failed to queue status change event", "product_id", productID, "error", err)
+		return err
+	}
+
+	s.logger.Infow("product status change queued", "restaurant_id", menu.RestaurantID, "branch_id", branchID, "product_id", productID, "old_status", oldStatus, "new_status", newStatus)
+
+	if scheduled {
+		s.logger.Infow("product status restore scheduled", "restaurant_id", menu.RestaurantID, "branch_id", branchID, "product_id", productID, "restore_status", oldStatus, "run_at", until)
+	}
+
+	return nil
+}
+
+// UpdateAttributeStatus changes the status of an attribute (modifier) in the
+// current menu of a restaurant and all its branches, or only in the branch
+// with branchID. The change is applied and audited by the product-status
+// worker like product status changes.
+func (s *ProductService) UpdateAttributeStatus(ctx context.Context, restaurantID, branchID, attributeID, newStatus, reason, userID string) error {
+	menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+	if err != nil {
+		return fmt.Errorf("failed to find menu: %w", err)
+	}
+
+	if findAttribute(menu, attributeID) == nil {
+		return domain.ErrAttributeNotFound
+	}
+
+	oldStatus, err := s.attributeStatus(ctx, menu, branchID, attributeID)
+	if err != nil {
+		return err
+	}
+
+	event := domain.ProductStatusEvent{
+		EventType:    domain.EventAttributeStatusChanged,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menu.ID.Hex(),
+		AttributeID:  attributeID,
+		BranchID:     branchID,
+		OldStatus:    oldStatus,
+		NewStatus:    newStatus,
+		Reason:       reason,
+		UserID:       userID,
+	}
+
+	return s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		sequence, err := s.sequenceRepo.Next(ctx, menu.RestaurantID, domain.EntityAttribute, attributeID)
+		if err != nil {
+			return err
+		}
+		event.Sequence = sequence
+
+		return s.publishEvent(ctx, event)
+	})
+}
+
+// productStatus returns the status of a product of the menu, overridden by
+// the branch if branchID is set.
+func (s *ProductService) productStatus(ctx context.Context, menu *domain.Menu, branchID, productID string) (string, error) {
+	status := findProduct(menu, productID).Status
+	if branchID == "" {
+		return status, nil
+	}
+
+	branch, err := s.branchRepo.GetByID(ctx, menu.RestaurantID, branchID)
+	if err != nil {
+		return "", err
+	}
+
+	if override := branch.ProductOverride(productID); override != nil && override.Status != "" {
+		status = override.Status
+	}
+
+	return status, nil
+}
+
+// attributeStatus returns the status of an attribute of the menu, overridden
+// by the branch if branchID is set.
+func (s *ProductService) attributeStatus(ctx context.Context, menu *domain.Menu, branchID, attributeID string) (string, error) {
+	status := findAttribute(menu, attributeID).Status
+	if status == "" {
+		status = domain.AttributeStatusAvailable
+	}
+	if branchID == "" {
+		return status, nil
+	}
+
+	branch, err := s.branchRepo.GetByID(ctx, menu.RestaurantID, branchID)
+	if err != nil {
+		return "", err
+	}
+
+	if override := branch.AttributeOverride(attributeID); override != nil && override.Status != "" {
+		status = override.Status
+	}
+
+	return status, nil
+}
+
+// findProductMenu returns the current menu of the restaurant the product
+// belongs to. Without a restaurantID every restaurant that has ever had the
+// product is checked, and more than one match is ambiguous.
+func (s *ProductService) findProductMenu(ctx context.Context, restaurantID, productID string) (*domain.Menu, error) {
+	if restaurantID != "" {
+		menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+		if err != nil {
+			return nil, err
+		}
+		if findProduct(menu, productID) == nil {
+			return nil, domain.ErrProductNotFound
+		}
+		return menu, nil
+	}
+
+	restaurantIDs, err := s.menuRepo.FindRestaurantIDsByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *domain.Menu
+	for _, id := range restaurantIDs {
+		menu, err := s.findProductMenu(ctx, id, productID)
+		if errors.Is(err, domain.ErrProductNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: %q is used by restaurants %q and %q", domain.ErrProductAmbiguous, productID, found.RestaurantID, menu.RestaurantID)
+		}
+		found = menu
+	}
+
+	if found == nil {
+		return nil, domain.ErrProductNotFound
+	}
+
+	return found, nil
+}
+
+func (s *ProductService) BatchUpdateProductStatus(ctx context.Context, selector ProductSelector, newStatus, reason, userID string) ([]BatchStatusResult, error) {
+	results, err := s.selectProducts(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var queued []int
+	for i := range results {
+		if results[i].Result == BatchResultNotFound || results[i].Result == BatchResultAmbiguous {
+			continue
+		}
+		if results[i].OldStatus == newStatus {
+			results[i].Result = BatchResultUnchanged
+			continue
+		}
+		queued = append(queued, i)
+	}
+
+	if len(queued) == 0 {
+		return results, nil
+	}
+
+	// the event, its sequence numbers and the restores it replaces are
+	// stored together
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		items := make([]domain.ProductStatusEventItem, 0, len(queued))
+		for _, i := range queued {
+			sequence, err := s.sequenceRepo.Next(ctx, results[i].RestaurantID, domain.EntityProduct, results[i].ProductID)
+			if err != nil {
+				return err
+			}
+
+			items = append(items, domain.ProductStatusEventItem{
+				RestaurantID: results[i].RestaurantID,
+				MenuID:       results[i].MenuID,
+				ProductID:    results[i].ProductID,
+				Sequence:     sequence,
+				OldStatus:    results[i].OldStatus,
+			})
+		}
+
+		event := domain.ProductStatusEvent{
+			EventType: domain.EventProductStatusBatchChanged,
+			NewStatus: newStatus,
+			Reason:    reason,
+			UserID:    userID,
+			Items:     items,
+		}
+
+		if err := s.publishEvent(ctx, event); err != nil {
+			return err
+		}
+
+		// a manual change supersedes restores scheduled before it
+		for _, item := range items {
+			if err := s.restoreRepo.CancelPending(ctx, item.RestaurantID, "", item.ProductID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		s.logger.Errorw("failed to queue batch status change event", "products", len(queued), "error", err)
+		return nil, err
+	}
+
+	for _, i := range queued {
+		results[i].Result = BatchResultQueued
+	}
+
+	return results, nil
+}
+
+// selectProducts resolves a selector into products with their current status.
+// Repeated product IDs are resolved once. Results for unknown product IDs are
+// marked as not found, for IDs used by several restaurants as ambiguous.
+func (s *ProductService) selectProducts(ctx context.Context, selector ProductSelector) ([]BatchStatusResult, error) {
+	if len(selector.ProductIDs) > 0 {
+		results := make([]BatchStatusResult, 0, len(selector.ProductIDs))
+		seen := make(map[string]bool, len(selector.ProductIDs))
+		for _, productID := range selector.ProductIDs {
+			if seen[productID] {
+				continue
+			}
+			seen[productID] = true
+
+			menu, err := s.findProductMenu(ctx, "", productID)
+			if err != nil {
+				if errors.Is(err, domain.ErrProductNotFound) {
+					results = append(results, BatchStatusResult{ProductID: productID, Result: BatchResultNotFound})
+					continue
+				}
+				if errors.Is(err, domain.ErrProductAmbiguous) {
+					results = append(results, BatchStatusResult{ProductID: productID, Result: BatchResultAmbiguous})
+					continue
+				}
+				return nil, fmt.Errorf("failed to find product: %w", err)
+			}
+
+			product := findProduct(menu, productID)
+			results = append(results, BatchStatusResult{
+				ProductID:    productID,
+				RestaurantID: menu.RestaurantID,
+				MenuID:       menu.ID.Hex(),
+				OldStatus:    product.Status,
+			})
+		}
+		return results, nil
+	}
+
+	menu, err := s.menuRepo.GetByRestaurantID(ctx, selector.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// attribute groups that offer the selected attribute
+	groups := make(map[string]bool)
+	for _, group := range menu.AttributeGroups {
+		if selector.AttributeID != "" && contains(group.Attributes, selector.AttributeID) {
+			groups[group.ID] = true
+		}
+	}
+
+	results := []BatchStatusResult{}
 	for _, product := range menu.Products {
-		if product.ID == productID {
-			oldStatus = product.Status
-			found = true
-			break
+		if selector.Category != "" && product.Category != selector.Category {
+			continue
+		}
+		if selector.AttributeID != "" && !usesAnyGroup(product, groups) {
+			continue
+		}
+
+		results = append(results, BatchStatusResult{
+			ProductID:    product.ID,
+			RestaurantID: menu.RestaurantID,
+			MenuID:       menu.ID.Hex(),
+			OldStatus:    product.Status,
+		})
+	}
+
+	return results, nil
+}
+
+func usesAnyGroup(product domain.Product, groups map[string]bool) bool {
+	for _, groupID := range product.Attributes {
+		if groups[groupID] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *ProductService) CreateProduct(ctx context.Context, menuID primitive.ObjectID, version int64, product domain.Product, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	if findProduct(menu, product.ID) != nil {
+		return domain.ErrProductExists
+	}
+
+	if product.Status == "" {
+		product.Status = domain.ProductStatusAvailable
+	}
+
+	if err := validateProduct(menu, &product); err != nil {
+		return err
+	}
+
+	event := domain.ProductStatusEvent{
+		EventType:    domain.EventProductCreated,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menuID.Hex(),
+		ProductID:    product.ID,
+		Product:      &product,
+		NewStatus:    product.Status,
+		UserID:       userID,
+	}
+
+	return s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.ReserveVersion(ctx, menuID, version); err != nil {
+			return err
+		}
+
+		// the initial status is numbered like status changes, so changes
+		// queued for an earlier product with the same ID can't override it
+		sequence, err := s.sequenceRepo.Next(ctx, menu.RestaurantID, domain.EntityProduct, product.ID)
+		if err != nil {
+			return err
 		}
+		event.Sequence = sequence
+
+		return s.publishEvent(ctx, event)
+	})
+}
+
+func (s *ProductService) UpdateProduct(ctx context.Context, menuID primitive.ObjectID, version int64, product domain.Product, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	current := findProduct(menu, product.ID)
+	if current == nil {
+		return domain.ErrProductNotFound
 	}
 
-	if !found {
-		return fmt.Errorf("product not found")
+	// status changes are ordered by the status endpoints, the worker keeps
+	// the status the product has when the update is applied
+	if product.Status != "" && product.Status != current.Status {
+		return fmt.Errorf("%w: status can't be changed by an update, use the product status endpoint", domain.ErrInvalidProduct)
+	}
+	product.Status = current.Status
+
+	if err := validateProduct(menu, &product); err != nil {
+		return err
 	}
 
-	// publish status change event (worker will update DB)
 	event := domain.ProductStatusEvent{
-		EventType: domain.EventProductStatusChanged,
-		ProductID: productID,
-		OldStatus: oldStatus,
-		NewStatus: newStatus,
-		Reason:    reason,
-		UserID:    userID,
+		EventType:    domain.EventProductUpdated,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menuID.Hex(),
+		ProductID:    product.ID,
+		Product:      &product,
+		OldProduct:   current,
+		OldStatus:    current.Status,
+		NewStatus:    product.Status,
+		UserID:       userID,
 	}
 
-	eventBytes, err := json.Marshal(event)
+	return s.queueMenuChange(ctx, menuID, version, event)
+}
+
+func (s *ProductService) DeleteProduct(ctx context.Context, menuID primitive.ObjectID, version int64, productID, userID string) error {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal event: %w", err)
+		return err
 	}
 
-	if err := s.broker.Publish(ctx, queue.QueueProductStatus, eventBytes); err != nil {
-		s.logger.Errorw("failed to publish status change event", "product_id", productID, "error", err)
-		return fmt.Errorf("failed to publish event: %w", err)
+	if menu.Version != version {
+		return domain.ErrVersionConflict
 	}
 
-	s.logger.Infow("product status change queued", "product_id", productID, "old_status", oldStatus, "new_status", newStatus)
+	current := findProduct(menu, productID)
+	if current == nil {
+		return domain.ErrProductNotFound
+	}
+
+	event := domain.ProductStatusEvent{
+		EventType:    domain.EventProductDeleted,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menuID.Hex(),
+		ProductID:    productID,
+		OldProduct:   current,
+		OldStatus:    current.Status,
+		NewStatus:    domain.ProductStatusDeleted,
+		UserID:       userID,
+	}
+
+	return s.queueMenuChange(ctx, menuID, version, event)
+}
+
+// queueMenuChange reserves the menu version the change was made against and
+// queues its event, so of two changes made against the same version only the
+// first is accepted. The worker applies accepted changes whatever the version
+// is by then.
+func (s *ProductService) queueMenuChange(ctx context.Context, menuID primitive.ObjectID, version int64, event domain.ProductStatusEvent) error {
+	return s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.menuRepo.ReserveVersion(ctx, menuID, version); err != nil {
+			return err
+		}
+
+		return s.publishEvent(ctx, event)
+	})
+}
+
+// publishEvent stores the event in the outbox under a new ID, the outbox
+// relay publishes it to the product-status queue.
+func (s *ProductService) publishEvent(ctx context.Context, event domain.ProductStatusEvent) error {
+	event.ID = primitive.NewObjectID().Hex()
+	if err := enqueue(ctx, s.outboxRepo, queue.QueueProductStatus, event); err != nil {
+		s.logger.Errorw("failed to queue product event", "product_id", event.ProductID, "event_type", event.EventType, "error", err)
+		return fmt.Errorf("failed to queue event: %w", err)
+	}
+
+	s.logger.Infow("product event queued", "event_id", event.ID, "menu_id", event.MenuID, "product_id", event.ProductID, "attribute_id", event.AttributeID, "event_type", event.EventType)
 
 	return nil
 }
 
+// ProcessProductStatusEvent applies the event and writes its audit records in
+// one transaction, a failed audit write leaves the menus unchanged. The event
+// ID is recorded in the same transaction, an event applied before returns
+// domain.ErrEventAlreadyProcessed. Events queued before they carried an ID are
+// always applied. Status changes older than one already applied are not made
+// and are audited as rejected.
 func (s *ProductService) ProcessProductStatusEvent(ctx context.Context, event domain.ProductStatusEvent) error {
-	session, err := s.storage.StartSession()
+	err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if event.ID != "" {
+			processed := &domain.ProcessedEvent{ID: event.ID, EventType: event.EventType}
+			if err := s.eventRepo.Create(ctx, processed); err != nil {
+				return err
+			}
+		}
+
+		applied, rejected, err := s.orderEvent(ctx, event)
+		if err != nil {
+			return err
+		}
+
+		var (
+			audits   []*domain.ProductStatusAudit
+			entries  []*domain.AuditLogEntry
+			webhooks []domain.WebhookEvent
+		)
+
+		// apply the change in database
+		if applied != nil {
+			if err := s.applyEvent(ctx, *applied); err != nil {
+				s.logger.Errorw("failed to apply product event", "product_id", event.ProductID, "event_type", event.EventType, "error", err)
+				return fmt.Errorf("failed to apply product event: %w", err)
+			}
+			audits = auditRecords(*applied)
+			entries = auditLogEntries(*applied)
+			webhooks = webhookEvents(*applied)
+		}
+
+		if rejected != nil {
+			s.logger.Warnw("rejecting stale status change", "event_id", event.ID, "restaurant_id", event.RestaurantID, "product_id", event.ProductID, "attribute_id", event.AttributeID, "sequence", event.Sequence, "items", len(rejected.Items))
+			for _, audit := range auditRecords(*rejected) {
+				audit.Rejected = true
+				audits = append(audits, audit)
+			}
+		}
+
+		// create audit records
+		for _, audit := range audits {
+			if err := s.auditRepo.Create(ctx, audit); err != nil {
+				s.logger.Errorw("failed to create audit record", "product_id", audit.ProductID, "error", err)
+				return fmt.Errorf("failed to create audit record: %w", err)
+			}
+		}
+
+		for _, entry := range entries {
+			if err := writeAuditLog(ctx, s.auditLogRepo, s.logger, entry); err != nil {
+				return err
+			}
+		}
+
+		for _, webhookEvent := range webhooks {
+			if err := queueWebhookEvent(ctx, s.webhookRepo, s.deliveryRepo, webhookEvent); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("product event applied", "event_id", event.ID, "product_id", event.ProductID, "event_type", event.EventType, "new_status", event.NewStatus)
+
+	return nil
+}
+
+// orderEvent splits the status changes of the event into the ones newer than
+// everything applied to what they change, which are recorded as applied, and
+// stale ones. Applied changes get the status they replace now as their old
+// status, the one read when they were requested may have changed since.
+// Created products record their initial status as applied, updated ones keep
+// their current status. Changes queued before they were numbered and other
+// events are applied as they are.
+func (s *ProductService) orderEvent(ctx context.Context, event domain.ProductStatusEvent) (applied, rejected *domain.ProductStatusEvent, err error) {
+	switch event.EventType {
+	case domain.EventProductStatusChanged, domain.EventAttributeStatusChanged:
+		if event.Sequence == 0 {
+			return &event, nil, nil
+		}
+
+		entityType, entityID := domain.EntityProduct, event.ProductID
+		if event.EventType == domain.EventAttributeStatusChanged {
+			entityType, entityID = domain.EntityAttribute, event.AttributeID
+		}
+
+		accepted, err := s.acceptChange(ctx, event.RestaurantID, entityType, entityID, event.BranchID, event.Sequence)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !accepted {
+			return nil, &event, nil
+		}
+
+		menu, err := s.eventMenu(ctx, event.MenuID)
+		if err != nil {
+			return nil, nil, err
+		}
+		event.OldStatus, err = s.currentStatus(ctx, menu, event.BranchID, entityType, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return &event, nil, nil
+	case domain.EventProductStatusBatchChanged:
+		var appliedItems, rejectedItems []domain.ProductStatusEventItem
+		menus := make(map[string]*domain.Menu)
+		for _, item := range event.Items {
+			if item.Sequence == 0 {
+				appliedItems = append(appliedItems, item)
+				continue
+			}
+
+			accepted, err := s.acceptChange(ctx, item.RestaurantID, domain.EntityProduct, item.ProductID, "", item.Sequence)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !accepted {
+				rejectedItems = append(rejectedItems, item)
+				continue
+			}
+
+			menu, ok := menus[item.MenuID]
+			if !ok {
+				menu, err = s.eventMenu(ctx, item.MenuID)
+				if err != nil {
+					return nil, nil, err
+				}
+				menus[item.MenuID] = menu
+			}
+			item.OldStatus, err = s.currentStatus(ctx, menu, "", domain.EntityProduct, item.ProductID)
+			if err != nil {
+				return nil, nil, err
+			}
+			appliedItems = append(appliedItems, item)
+		}
+
+		if len(appliedItems) > 0 {
+			batch := event
+			batch.Items = appliedItems
+			applied = &batch
+		}
+		if len(rejectedItems) > 0 {
+			batch := event
+			batch.Items = rejectedItems
+			rejected = &batch
+		}
+
+		return applied, rejected, nil
+	case domain.EventProductCreated:
+		if event.Sequence != 0 {
+			// the product is new, nothing newer can have been applied to it
+			if _, err := s.acceptChange(ctx, event.RestaurantID, domain.EntityProduct, event.ProductID, "", event.Sequence); err != nil {
+				return nil, nil, err
+			}
+		}
+		return &event, nil, nil
+	case domain.EventProductUpdated:
+		if event.Product == nil {
+			return &event, nil, nil
+		}
+
+		menu, err := s.eventMenu(ctx, event.MenuID)
+		if err != nil {
+			return nil, nil, err
+		}
+		current := findProduct(menu, event.ProductID)
+		if current == nil {
+			return nil, nil, domain.ErrProductNotFound
+		}
+
+		// updates don't change the status, keep the one applied since
+		product := *event.Product
+		product.Status = current.Status
+		event.Product = &product
+		if event.OldProduct != nil {
+			oldProduct := *event.OldProduct
+			oldProduct.Status = current.Status
+			event.OldProduct = &oldProduct
+		}
+		event.OldStatus, event.NewStatus = current.Status, current.Status
+
+		return &event, nil, nil
+	default:
+		return &event, nil, nil
+	}
+}
+
+// acceptChange reports whether the numbered status change is newer than
+// everything applied to what it changes, and records it as applied if so.
+func (s *ProductService) acceptChange(ctx context.Context, restaurantID, entityType, entityID, branchID string, seq int64) (bool, error) {
+	sequence, err := s.sequenceRepo.Get(ctx, restaurantID, entityType, entityID)
 	if err != nil {
-		s.logger.Errorw("failed to start session", "product_id", event.ProductID, "error", err)
-		return fmt.Errorf("failed to start session: %w", err)
+		return false, err
 	}
-	defer session.EndSession(ctx)
 
-	err = session.StartTransaction()
+	if !sequence.Accept(branchID, seq) {
+		return false, nil
+	}
+
+	return true, s.sequenceRepo.SaveApplied(ctx, sequence)
+}
+
+func (s *ProductService) eventMenu(ctx context.Context, menuID string) (*domain.Menu, error) {
+	id, err := primitive.ObjectIDFromHex(menuID)
 	if err != nil {
-		s.logger.Errorw("failed to start transaction", "product_id", event.ProductID, "error", err)
-		return fmt.Errorf("failed to start transaction: %w", err)
+		return nil, fmt.Errorf("invalid menu ID: %w", err)
 	}
 
-	// update product status in database
-	if err := s.menuRepo.UpdateProductStatusByProductID(ctx, event.ProductID, event.NewStatus); err != nil {
-		s.logger.Errorw("failed to update product status", "product_id", event.ProductID, "error", err)
-		session.AbortTransaction(ctx)
-		return fmt.Errorf("failed to update product status: %w", err)
+	return s.menuRepo.GetByID(ctx, id)
+}
+
+// currentStatus returns the status of the product or attribute of the menu,
+// overridden by the branch if branchID is set.
+func (s *ProductService) currentStatus(ctx context.Context, menu *domain.Menu, branchID, entityType, entityID string) (string, error) {
+	if entityType == domain.EntityAttribute {
+		if findAttribute(menu, entityID) == nil {
+			return "", domain.ErrAttributeNotFound
+		}
+		return s.attributeStatus(ctx, menu, branchID, entityID)
 	}
 
-	s.logger.Infow("product status updated in DB", "product_id", event.ProductID, "new_status", event.NewStatus)
+	if findProduct(menu, entityID) == nil {
+		return "", domain.ErrProductNotFound
+	}
+	return s.productStatus(ctx, menu, branchID, entityID)
+}
 
-	// create audit record
-	audit := &domain.ProductStatusAudit{
-		ProductID: event.ProductID,
-		EventType: event.EventType,
-		OldStatus: event.OldStatus,
-		NewStatus: event.NewStatus,
-		Reason:    event.Reason,
-		UserID:    event.UserID,
-		Timestamp: event.Timestamp,
+func (s *ProductService) applyEvent(ctx context.Context, event domain.ProductStatusEvent) error {
+	switch event.EventType {
+	case domain.EventProductStatusChanged:
+		return s.applyStatusChange(ctx, event)
+	case domain.EventProductStatusBatchChanged:
+		for _, item := range event.Items {
+			menuID, err := primitive.ObjectIDFromHex(item.MenuID)
+			if err != nil {
+				return fmt.Errorf("invalid menu ID: %w", err)
+			}
+			if err := s.menuRepo.UpdateProductStatus(ctx, menuID, item.ProductID, event.NewStatus); err != nil {
+				return fmt.Errorf("failed to update product %s: %w", item.ProductID, err)
+			}
+			if item.RestaurantID != "" {
+				if err := s.branchRepo.ClearProductStatus(ctx, item.RestaurantID, item.ProductID); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	}
 
-	if err := s.auditRepo.Create(ctx, audit); err != nil {
-		s.logger.Errorw("failed to create audit record", "product_id", event.ProductID, "error", err)
-		session.AbortTransaction(ctx)
-		return fmt.Errorf("failed to create audit record: %w", err)
+	menuID, err := primitive.ObjectIDFromHex(event.MenuID)
+	if err != nil {
+		return fmt.Errorf("invalid menu ID: %w", err)
 	}
 
-	// commit transaction
-	if err := session.CommitTransaction(ctx); err != nil {
-		s.logger.Errorw("failed to commit transaction", "product_id", event.ProductID, "error", err)
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	switch event.EventType {
+	case domain.EventProductCreated:
+		if event.Product == nil {
+			return fmt.Errorf("%w: event has no product", domain.ErrInvalidProduct)
+		}
+		return s.menuRepo.AddProduct(ctx, menuID, event.Product)
+	case domain.EventProductUpdated:
+		if event.Product == nil {
+			return fmt.Errorf("%w: event has no product", domain.ErrInvalidProduct)
+		}
+		return s.menuRepo.ReplaceProduct(ctx, menuID, event.Product)
+	case domain.EventProductDeleted:
+		return s.menuRepo.RemoveProduct(ctx, menuID, event.ProductID)
+	case domain.EventAttributeStatusChanged:
+		if event.BranchID != "" {
+			return s.branchRepo.SetAttributeStatus(ctx, event.RestaurantID, event.BranchID, event.AttributeID, event.NewStatus)
+		}
+		if err := s.menuRepo.UpdateAttributeStatus(ctx, menuID, event.AttributeID, event.NewStatus); err != nil {
+			return err
+		}
+		return s.branchRepo.ClearAttributeStatus(ctx, event.RestaurantID, event.AttributeID)
+	default:
+		return fmt.Errorf("unknown event type %q", event.EventType)
 	}
+}
 
-	s.logger.Infow("product status audit created", "product_id", event.ProductID, "event_type", event.EventType)
+// applyStatusChange updates the product in the menu it was resolved in when
+// the change was requested, and drops the branch overrides of its status so
+// the change applies to all branches. Branch events only override the status
+// in their branch. Events queued without a menu are resolved to the
+// restaurant's current menu, or to the latest menu containing the product when
+// they carry no restaurant either.
+func (s *ProductService) applyStatusChange(ctx context.Context, event domain.ProductStatusEvent) error {
+	if event.BranchID != "" {
+		return s.branchRepo.SetProductStatus(ctx, event.RestaurantID, event.BranchID, event.ProductID, event.NewStatus)
+	}
 
-	return nil
+	if event.MenuID == "" {
+		var (
+			menu *domain.Menu
+			err  error
+		)
+		if event.RestaurantID != "" {
+			menu, err = s.menuRepo.GetByRestaurantID(ctx, event.RestaurantID)
+		} else {
+			menu, err = s.menuRepo.FindMenuByProductID(ctx, event.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.menuRepo.UpdateProductStatus(ctx, menu.ID, event.ProductID, event.NewStatus); err != nil {
+			return err
+		}
+		return s.branchRepo.ClearProductStatus(ctx, menu.RestaurantID, event.ProductID)
+	}
+
+	menuID, err := primitive.ObjectIDFromHex(event.MenuID)
+	if err != nil {
+		return fmt.Errorf("invalid menu ID: %w", err)
+	}
+
+	if err := s.menuRepo.UpdateProductStatus(ctx, menuID, event.ProductID, event.NewStatus); err != nil {
+		return err
+	}
+
+	// events queued before restaurants were tracked have no branches to clear
+	if event.RestaurantID == "" {
+		return nil
+	}
+
+	return s.branchRepo.ClearProductStatus(ctx, event.RestaurantID, event.ProductID)
+}
+
+// StopList returns the unavailable products of the restaurant's current menu
+// with the reason, user and time of their last status change. Deleted
+// products are not on sale anymore and are left out.
+func (s *ProductService) StopList(ctx context.Context, restaurantID string) ([]domain.StopListEntry, error) {
+	menu, err := s.menuRepo.GetByRestaurantID(ctx, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs []string
+	for _, product := range menu.Products {
+		if onStopList(product) {
+			productIDs = append(productIDs, product.ID)
+		}
+	}
+
+	entries := []domain.StopListEntry{}
+	if len(productIDs) == 0 {
+		return entries, nil
+	}
+
+	audits, err := s.auditRepo.GetLatestByProductIDs(ctx, restaurantID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range menu.Products {
+		if !onStopList(product) {
+			continue
+		}
+
+		entry := domain.StopListEntry{
+			ProductID: product.ID,
+			Name:      product.Name,
+			Category:  product.Category,
+			Status:    product.Status,
+		}
+		if audit, ok := audits[product.ID]; ok {
+			changedAt := audit.Timestamp
+			entry.Reason = audit.Reason
+			entry.UserID = audit.UserID
+			entry.ChangedAt = &changedAt
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
 }
 
-func (s *ProductService) GetProductAudit(ctx context.Context, productID string, limit int) ([]domain.ProductStatusAudit, error) {
-	audits, err := s.auditRepo.GetByProductID(ctx, productID, limit)
+func onStopList(product domain.Product) bool {
+	return product.Status != domain.ProductStatusAvailable && product.Status != domain.ProductStatusDeleted
+}
+
+// GetProductAudit returns a page of the product's status history, newest
+// first. cursor is the NextCursor of the previous page, empty for the first
+// one.
+func (s *ProductService) GetProductAudit(ctx context.Context, filter domain.ProductStatusAuditFilter, cursor string) (*domain.ProductStatusAuditPage, error) {
+	return s.auditPage(ctx, filter, cursor, s.auditRepo.GetByProductID)
+}
+
+// GetRestaurantAudit returns a page of the status history of every product
+// and attribute of the restaurant, newest first.
+func (s *ProductService) GetRestaurantAudit(ctx context.Context, filter domain.ProductStatusAuditFilter, cursor string) (*domain.ProductStatusAuditPage, error) {
+	return s.auditPage(ctx, filter, cursor, s.auditRepo.GetByRestaurantID)
+}
+
+// ExportAudit calls write with every audit record in the filter's time range,
+// oldest first, without loading them all into memory.
+func (s *ProductService) ExportAudit(ctx context.Context, filter domain.ProductStatusAuditFilter, write func(audit domain.ProductStatusAudit) error) error {
+	return s.auditRepo.Stream(ctx, filter, write)
+}
+
+func (s *ProductService) auditPage(
+	ctx context.Context,
+	filter domain.ProductStatusAuditFilter,
+	cursor string,
+	find func(ctx context.Context, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error),
+) (*domain.ProductStatusAuditPage, error) {
+	if cursor != "" {
+		timestamp, id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterTimestamp = &timestamp
+		filter.AfterID = id
+	}
+
+	// one extra record tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	audits, err := find(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get product audit: %w", err)
+		return nil, fmt.Errorf("failed to get audit: %w", err)
+	}
+
+	page := &domain.ProductStatusAuditPage{Items: audits}
+	if len(audits) > limit {
+		page.Items = audits[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeAuditCursor(last.Timestamp, last.ID)
+	}
+
+	return page, nil
+}
+
+// encodeAuditCursor returns the cursor of the page following a record with
+// the given timestamp and ID.
+func encodeAuditCursor(timestamp time.Time, id primitive.ObjectID) string {
+	raw := strconv.FormatInt(timestamp.UnixNano(), 10) + "_" + id.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeAuditCursor(cursor string) (time.Time, primitive.ObjectID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, domain.ErrInvalidCursor
+	}
+
+	nanos, hex, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return time.Time{}, primitive.NilObjectID, domain.ErrInvalidCursor
+	}
+
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, domain.ErrInvalidCursor
+	}
+
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, domain.ErrInvalidCursor
+	}
+
+	return time.Unix(0, unixNano), id, nil
+}
+
+// auditRecords builds one audit record per product affected by the event.
+// Menu IDs were validated when the event was applied, events queued before
+// they carried a menu get none.
+func auditRecords(event domain.ProductStatusEvent) []*domain.ProductStatusAudit {
+	if event.EventType != domain.EventProductStatusBatchChanged {
+		menuID, _ := primitive.ObjectIDFromHex(event.MenuID)
+		return []*domain.ProductStatusAudit{{
+			RestaurantID: event.RestaurantID,
+			MenuID:       menuID,
+			ProductID:    event.ProductID,
+			AttributeID:  event.AttributeID,
+			BranchID:     event.BranchID,
+			EventType:    event.EventType,
+			OldStatus:    event.OldStatus,
+			NewStatus:    event.NewStatus,
+			Reason:       event.Reason,
+			UserID:       event.UserID,
+			Timestamp:    event.Timestamp,
+		}}
+	}
+
+	audits := make([]*domain.ProductStatusAudit, 0, len(event.Items))
+	for _, item := range event.Items {
+		menuID, _ := primitive.ObjectIDFromHex(item.MenuID)
+		audits = append(audits, &domain.ProductStatusAudit{
+			RestaurantID: item.RestaurantID,
+			MenuID:       menuID,
+			ProductID:    item.ProductID,
+			EventType:    event.EventType,
+			OldStatus:    item.OldStatus,
+			NewStatus:    event.NewStatus,
+			Reason:       event.Reason,
+			UserID:       event.UserID,
+			Timestamp:    event.Timestamp,
+		})
+	}
+
+	return audits
+}
+
+// auditLogEntries builds the audit log entries of the changes made by the
+// event. Status snapshots of branch events carry the branch they apply to.
+func auditLogEntries(event domain.ProductStatusEvent) []*domain.AuditLogEntry {
+	if event.EventType == domain.EventProductStatusBatchChanged {
+		entries := make([]*domain.AuditLogEntry, 0, len(event.Items))
+		for _, item := range event.Items {
+			menuID, _ := primitive.ObjectIDFromHex(item.MenuID)
+			entries = append(entries, &domain.AuditLogEntry{
+				Actor:        event.UserID,
+				Action:       domain.ProductActionStatusChanged,
+				EntityType:   domain.EntityProduct,
+				EntityID:     item.ProductID,
+				RestaurantID: item.RestaurantID,
+				MenuID:       menuID,
+				Before:       bson.M{"status": item.OldStatus},
+				After:        bson.M{"status": event.NewStatus, "reason": event.Reason},
+				Timestamp:    event.Timestamp,
+			})
+		}
+		return entries
+	}
+
+	menuID, _ := primitive.ObjectIDFromHex(event.MenuID)
+	entry := &domain.AuditLogEntry{
+		Actor:        event.UserID,
+		EntityType:   domain.EntityProduct,
+		EntityID:     event.ProductID,
+		RestaurantID: event.RestaurantID,
+		MenuID:       menuID,
+		Timestamp:    event.Timestamp,
+	}
+
+	switch event.EventType {
+	case domain.EventProductCreated:
+		entry.Action = domain.ProductActionCreated
+		entry.After = snapshot(event.Product)
+	case domain.EventProductUpdated:
+		entry.Action = domain.ProductActionUpdated
+		entry.Before = snapshot(event.OldProduct)
+		entry.After = snapshot(event.Product)
+	case domain.EventProductDeleted:
+		entry.Action = domain.ProductActionDeleted
+		entry.Before = snapshot(event.OldProduct)
+	case domain.EventProductStatusChanged, domain.EventAttributeStatusChanged:
+		entry.Action = domain.ProductActionStatusChanged
+		if event.EventType == domain.EventAttributeStatusChanged {
+			entry.Action = domain.AttributeActionStatusChanged
+			entry.EntityType = domain.EntityAttribute
+			entry.EntityID = event.AttributeID
+		}
+		entry.Before = bson.M{"status": event.OldStatus}
+		entry.After = bson.M{"status": event.NewStatus, "reason": event.Reason}
+		if event.BranchID != "" {
+			entry.Before["branch_id"] = event.BranchID
+			entry.After["branch_id"] = event.BranchID
+		}
 	}
 
-	return audits, nil
+	return []*domain.AuditLogEntry{entry}
+}
+
+// webhookEvents builds the webhook events of the changes made by the event.
+// Batch status changes are sent as a status change of each product.
+func webhookEvents(event domain.ProductStatusEvent) []domain.WebhookEvent {
+	if event.EventType == domain.EventProductStatusBatchChanged {
+		events := make([]domain.WebhookEvent, 0, len(event.Items))
+		for _, item := range event.Items {
+			events = append(events, domain.WebhookEvent{
+				Type:         domain.EventProductStatusChanged,
+				RestaurantID: item.RestaurantID,
+				OccurredAt:   event.Timestamp,
+				Data: map[string]interface{}{
+					"menu_id":    item.MenuID,
+					"product_id": item.ProductID,
+					"old_status": item.OldStatus,
+					"new_status": event.NewStatus,
+					"reason":     event.Reason,
+					"user_id":    event.UserID,
+				},
+			})
+		}
+		return events
+	}
+
+	data := map[string]interface{}{
+		"menu_id": event.MenuID,
+		"user_id": event.UserID,
+	}
+
+	switch event.EventType {
+	case domain.EventProductCreated, domain.EventProductUpdated:
+		data["product"] = event.Product
+	case domain.EventProductDeleted:
+		data["product_id"] = event.ProductID
+	case domain.EventProductStatusChanged, domain.EventAttributeStatusChanged:
+		if event.EventType == domain.EventAttributeStatusChanged {
+			data["attribute_id"] = event.AttributeID
+		} else {
+			data["product_id"] = event.ProductID
+		}
+		if event.BranchID != "" {
+			data["branch_id"] = event.BranchID
+		}
+		data["old_status"] = event.OldStatus
+		data["new_status"] = event.NewStatus
+		data["reason"] = event.Reason
+	}
+
+	return []domain.WebhookEvent{{
+		Type:         event.EventType,
+		RestaurantID: event.RestaurantID,
+		OccurredAt:   event.Timestamp,
+		Data:         data,
+	}}
+}
+
+func findProduct(menu *domain.Menu, productID string) *domain.Product {
+	for i := range menu.Products {
+		if menu.Products[i].ID == productID {
+			return &menu.Products[i]
+		}
+	}
+	return nil
+}
+
+// validateProduct checks a product against the menu it belongs to.
+func validateProduct(menu *domain.Menu, product *domain.Product) error {
+	if product.Status != domain.ProductStatusAvailable && product.Status != domain.ProductStatusNotAvailable {
+		return fmt.Errorf("%w: unsupported status %q", domain.ErrInvalidProduct, product.Status)
+	}
+
+	groups := make(map[string]bool, len(menu.AttributeGroups))
+	for _, group := range menu.AttributeGroups {
+		groups[group.ID] = true
+	}
+
+	seen := make(map[string]bool, len(product.Attributes))
+	for _, groupID := range product.Attributes {
+		if !groups[groupID] {
+			return fmt.Errorf("%w: unknown attribute group %q", domain.ErrInvalidProduct, groupID)
+		}
+		if seen[groupID] {
+			return fmt.Errorf("%w: duplicate attribute group %q", domain.ErrInvalidProduct, groupID)
+		}
+		seen[groupID] = true
+	}
+
+	if product.Attributes == nil {
+		product.Attributes = []string{}
+	}
+
+	return nil
 }
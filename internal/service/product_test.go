@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+var errAuditWrite = errors.New("audit write failed")
+
+// failingAuditRepo fails every audit record write.
+type failingAuditRepo struct {
+	repo.ProductStatusAuditRepository
+}
+
+func (r failingAuditRepo) Create(ctx context.Context, audit *domain.ProductStatusAudit) error {
+	return errAuditWrite
+}
+
+// newTestStorage connects to the replica set at MONGO_TEST_URI and returns
+// storage on a fresh database dropped when the test ends. The test is skipped
+// when MONGO_TEST_URI is unset.
+func newTestStorage(t *testing.T) *mongo.Storage {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	storage, err := mongo.New(mongo.Config{
+		URI:      uri,
+		Database: "kwaaka_test_" + primitive.NewObjectID().Hex(),
+		Timeout:  10 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to mongodb: %v", err)
+	}
+	t.Cleanup(func() {
+		ctx := context.Background()
+		if err := storage.Database().Drop(ctx); err != nil {
+			t.Errorf("failed to drop test database: %v", err)
+		}
+		storage.Close(ctx)
+	})
+
+	// collections must exist before they are written in a transaction
+	if err := storage.CreateIndexes(context.Background()); err != nil {
+		t.Fatalf("failed to create indexes: %v", err)
+	}
+
+	return storage
+}
+
+func newTestProductService(storage *mongo.Storage, auditRepo repo.ProductStatusAuditRepository) *ProductService {
+	db := storage.Database()
+
+	return NewProductService(
+		mongo.NewMenuRepository(db),
+		auditRepo,
+		mongo.NewAuditLogRepository(db),
+		mongo.NewScheduledRestoreRepository(db),
+		mongo.NewBranchRepository(db),
+		mongo.NewOutboxRepository(db),
+		mongo.NewProcessedEventRepository(db),
+		mongo.NewStatusSequenceRepository(db),
+		mongo.NewWebhookRepository(db),
+		mongo.NewWebhookDeliveryRepository(db),
+		storage,
+		zap.NewNop().Sugar(),
+	)
+}
+
+func TestProcessProductStatusEventRollsBackOnAuditFailure(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestStorage(t)
+	db := storage.Database()
+	menuRepo := mongo.NewMenuRepository(db)
+
+	menu := &domain.Menu{
+		Name:         "Test",
+		RestaurantID: "test-restaurant",
+		Products: []domain.Product{{
+			ID:         "1001",
+			Name:       "Burger",
+			Price:      1500,
+			Status:     domain.ProductStatusAvailable,
+			Attributes: []string{},
+		}},
+		AttributeGroups: []domain.AttributeGroup{},
+		Attributes:      []domain.Attribute{},
+	}
+	if err := menuRepo.Create(ctx, menu); err != nil {
+		t.Fatalf("failed to create menu: %v", err)
+	}
+
+	sequence, err := mongo.NewStatusSequenceRepository(db).Next(ctx, menu.RestaurantID, domain.EntityProduct, "1001")
+	if err != nil {
+		t.Fatalf("failed to issue sequence: %v", err)
+	}
+
+	event := domain.ProductStatusEvent{
+		ID:           primitive.NewObjectID().Hex(),
+		EventType:    domain.EventProductStatusChanged,
+		Sequence:     sequence,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menu.ID.Hex(),
+		ProductID:    "1001",
+		OldStatus:    domain.ProductStatusAvailable,
+		NewStatus:    domain.ProductStatusNotAvailable,
+		Reason:       "out of stock",
+		UserID:       "admin_123",
+		Timestamp:    time.Now(),
+	}
+
+	failing := newTestProductService(storage, failingAuditRepo{mongo.NewProductStatusAuditRepository(db)})
+	if err := failing.ProcessProductStatusEvent(ctx, event); !errors.Is(err, errAuditWrite) {
+		t.Fatalf("ProcessProductStatusEvent() error = %v, want %v", err, errAuditWrite)
+	}
+
+	got, err := menuRepo.GetByID(ctx, menu.ID)
+	if err != nil {
+		t.Fatalf("failed to get menu: %v", err)
+	}
+	if status := findProduct(got, "1001").Status; status != domain.ProductStatusAvailable {
+		t.Errorf("status after failed audit = %q, want %q", status, domain.ProductStatusAvailable)
+	}
+	if got.Version != menu.Version {
+		t.Errorf("menu version after failed audit = %d, want %d", got.Version, menu.Version)
+	}
+
+	// the event ID and the applied sequence were rolled back too, so the
+	// redelivered event is applied
+	working := newTestProductService(storage, mongo.NewProductStatusAuditRepository(db))
+	if err := working.ProcessProductStatusEvent(ctx, event); err != nil {
+		t.Fatalf("ProcessProductStatusEvent() on redelivery error = %v", err)
+	}
+
+	got, err = menuRepo.GetByID(ctx, menu.ID)
+	if err != nil {
+		t.Fatalf("failed to get menu: %v", err)
+	}
+	if status := findProduct(got, "1001").Status; status != domain.ProductStatusNotAvailable {
+		t.Errorf("status after redelivery = %q, want %q", status, domain.ProductStatusNotAvailable)
+	}
+}
+
+func TestAuditCursorRoundTrip(t *testing.T) {
+	timestamp := time.Date(2024, 3, 1, 12, 30, 45, 123456789, time.UTC)
+	id := primitive.NewObjectID()
+
+	gotTimestamp, gotID, err := decodeAuditCursor(encodeAuditCursor(timestamp, id))
+	if err != nil {
+		t.Fatalf("decodeAuditCursor() error = %v", err)
+	}
+	if !gotTimestamp.Equal(timestamp) {
+		t.Errorf("timestamp = %v, want %v", gotTimestamp, timestamp)
+	}
+	if gotID != id {
+		t.Errorf("id = %s, want %s", gotID.Hex(), id.Hex())
+	}
+}
+
+func TestDecodeAuditCursorMalformed(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	id := primitive.NewObjectID().Hex()
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte("1_" + id))},
+		{name: "no separator", cursor: encode("1709296245123456789" + id)},
+		{name: "empty timestamp", cursor: encode("_" + id)},
+		{name: "timestamp not a number", cursor: encode("yesterday_" + id)},
+		{name: "timestamp out of range", cursor: encode("99999999999999999999_" + id)},
+		{name: "empty id", cursor: encode("1709296245123456789_")},
+		{name: "id not hex", cursor: encode("1709296245123456789_zzzzzzzzzzzzzzzzzzzzzzzz")},
+		{name: "id too short", cursor: encode("1709296245123456789_" + id[:12])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeAuditCursor(tt.cursor); !errors.Is(err, domain.ErrInvalidCursor) {
+				t.Errorf("decodeAuditCursor(%q) error = %v, want %v", tt.cursor, err, domain.ErrInvalidCursor)
+			}
+		})
+	}
+}
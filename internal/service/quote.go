@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *MenuService) Quote(ctx context.Context, menuID primitive.ObjectID, req domain.QuoteRequest) (*domain.Quote, error) {
+	menu, err := s.menuRepo.GetByID(ctx, menuID)
+	if err != nil {
+		return nil, err
+	}
+
+	return calculateQuote(menu, req)
+}
+
+// calculateQuote validates the selected attributes against the product's
+// attribute groups and prices the order line.
+//
+// Group bounds apply to the total quantity picked within the group, attribute
+// bounds apply to the quantity of a selected attribute. A zero Max means no
+// upper bound. Unavailable attributes can't be selected.
+func calculateQuote(menu *domain.Menu, req domain.QuoteRequest) (*domain.Quote, error) {
+	product := findProduct(menu, req.ProductID)
+	if product == nil {
+		return nil, domain.ErrProductNotFound
+	}
+
+	if product.Status != domain.ProductStatusAvailable {
+		return nil, fmt.Errorf("%w: %q is %s", domain.ErrProductUnavailable, product.ID, product.Status)
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", domain.ErrInvalidQuote)
+	}
+
+	var problems []string
+	groupTotals := make(map[string]int, len(product.Attributes))
+	selected := make(map[string]bool, len(req.Selections))
+	lines := make([]domain.QuoteLine, 0, len(req.Selections))
+
+	for _, selection := range req.Selections {
+		attribute := findAttribute(menu, selection.AttributeID)
+		if attribute == nil {
+			problems = append(problems, fmt.Sprintf("unknown attribute %q", selection.AttributeID))
+			continue
+		}
+		if !attribute.Available() {
+			problems = append(problems, fmt.Sprintf("attribute %q is not available", attribute.ID))
+			continue
+		}
+
+		groupID, err := selectionGroup(menu, product, selection)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+
+		key := groupID + "/" + attribute.ID
+		if selected[key] {
+			problems = append(problems, fmt.Sprintf("attribute %q selected more than once in group %q", attribute.ID, groupID))
+			continue
+		}
+		selected[key] = true
+
+		count := selection.Quantity
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 || count < attribute.Min || (attribute.Max > 0 && count > attribute.Max) {
+			problems = append(problems, fmt.Sprintf("attribute %q has quantity %d, expected %s", attribute.ID, count, bounds(attribute.Min, attribute.Max)))
+			continue
+		}
+
+		groupTotals[groupID] += count
+		lines = append(lines, domain.QuoteLine{
+			AttributeID: attribute.ID,
+			GroupID:     groupID,
+			Name:        attribute.Name,
+			Quantity:    count,
+			UnitPrice:   attribute.Price,
+			Total:       roundPrice(attribute.Price * float64(count)),
+		})
+	}
+
+	for _, groupID := range product.Attributes {
+		group := findAttributeGroup(menu, groupID)
+		if group == nil {
+			continue
+		}
+
+		total := groupTotals[groupID]
+		if total < group.Min || (group.Max > 0 && total > group.Max) {
+			problems = append(problems, fmt.Sprintf("group %q has %d selected, expected %s", group.ID, total, bounds(group.Min, group.Max)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", domain.ErrInvalidQuote, strings.Join(problems, "; "))
+	}
+
+	unitPrice := product.Price
+	for _, line := range lines {
+		unitPrice += line.Total
+	}
+
+	return &domain.Quote{
+		MenuID:      menu.ID,
+		ProductID:   product.ID,
+		ProductName: product.Name,
+		Quantity:    quantity,
+		BasePrice:   product.Price,
+		Lines:       lines,
+		UnitPrice:   roundPrice(unitPrice),
+		Total:       roundPrice(unitPrice * float64(quantity)),
+	}, nil
+}
+
+// selectionGroup resolves the product group a selected attribute is picked from.
+func selectionGroup(menu *domain.Menu, product *domain.Product, selection domain.QuoteSelection) (string, error) {
+	if selection.GroupID != "" {
+		if !contains(product.Attributes, selection.GroupID) {
+			return "", fmt.Errorf("group %q is not offered for product %q", selection.GroupID, product.ID)
+		}
+		group := findAttributeGroup(menu, selection.GroupID)
+		if group == nil || !contains(group.Attributes, selection.AttributeID) {
+			return "", fmt.Errorf("attribute %q is not in group %q", selection.AttributeID, selection.GroupID)
+		}
+		return selection.GroupID, nil
+	}
+
+	var groupIDs []string
+	for _, groupID := range product.Attributes {
+		group := findAttributeGroup(menu, groupID)
+		if group != nil && contains(group.Attributes, selection.AttributeID) {
+			groupIDs = append(groupIDs, groupID)
+		}
+	}
+
+	switch len(groupIDs) {
+	case 0:
+		return "", fmt.Errorf("attribute %q is not offered for product %q", selection.AttributeID, product.ID)
+	case 1:
+		return groupIDs[0], nil
+	default:
+		return "", fmt.Errorf("attribute %q is in several groups (%s), group_id is required", selection.AttributeID, strings.Join(groupIDs, ", "))
+	}
+}
+
+func bounds(min, max int) string {
+	if max == 0 {
+		return fmt.Sprintf("at least %d", min)
+	}
+	return fmt.Sprintf("between %d and %d", min, max)
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
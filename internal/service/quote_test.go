@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func quoteTestMenu() *domain.Menu {
+	return &domain.Menu{
+		ID: primitive.NewObjectID(),
+		Products: []domain.Product{
+			{ID: "burger", Name: "Burger", Price: 1000, Status: domain.ProductStatusAvailable, Attributes: []string{"sauces", "sizes"}},
+			{ID: "cola", Name: "Cola", Price: 500, Status: domain.ProductStatusNotAvailable, Attributes: []string{}},
+			{ID: "combo", Name: "Combo", Price: 2000, Status: domain.ProductStatusAvailable, Attributes: []string{"toppings", "extras"}},
+		},
+		AttributeGroups: []domain.AttributeGroup{
+			{ID: "sauces", Max: 2, Attributes: []string{"ketchup", "mayo", "bbq"}},
+			{ID: "sizes", Min: 1, Max: 1, Attributes: []string{"small", "large"}},
+			{ID: "toppings", Attributes: []string{"cheese"}},
+			{ID: "extras", Attributes: []string{"cheese"}},
+		},
+		Attributes: []domain.Attribute{
+			{ID: "ketchup", Name: "Ketchup", Max: 3, Price: 50, Status: domain.AttributeStatusAvailable},
+			{ID: "mayo", Name: "Mayo", Price: 60.555, Status: domain.AttributeStatusAvailable},
+			{ID: "bbq", Name: "BBQ", Price: 70, Status: domain.AttributeStatusNotAvailable},
+			{ID: "small", Name: "Small", Status: domain.AttributeStatusAvailable},
+			{ID: "large", Name: "Large", Price: 300},
+			{ID: "cheese", Name: "Cheese", Price: 100, Status: domain.AttributeStatusAvailable},
+		},
+	}
+}
+
+func TestCalculateQuote(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       domain.QuoteRequest
+		wantErr   error
+		wantLines int
+		wantUnit  float64
+		wantTotal float64
+	}{
+		{
+			name:      "required group only",
+			req:       domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{{AttributeID: "small"}}},
+			wantLines: 1,
+			wantUnit:  1000,
+			wantTotal: 1000,
+		},
+		{
+			name: "modifiers and quantities",
+			req: domain.QuoteRequest{ProductID: "burger", Quantity: 3, Selections: []domain.QuoteSelection{
+				{AttributeID: "large"},
+				{AttributeID: "ketchup", Quantity: 2},
+			}},
+			wantLines: 2,
+			wantUnit:  1400,
+			wantTotal: 4200,
+		},
+		{
+			name: "prices are rounded to cents",
+			req: domain.QuoteRequest{ProductID: "burger", Quantity: 2, Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "mayo"},
+			}},
+			wantLines: 2,
+			wantUnit:  1060.56,
+			wantTotal: 2121.12,
+		},
+		{
+			name: "attribute in several groups with group_id",
+			req: domain.QuoteRequest{ProductID: "combo", Selections: []domain.QuoteSelection{
+				{AttributeID: "cheese", GroupID: "toppings"},
+				{AttributeID: "cheese", GroupID: "extras"},
+			}},
+			wantLines: 2,
+			wantUnit:  2200,
+			wantTotal: 2200,
+		},
+		{
+			name:    "unknown product",
+			req:     domain.QuoteRequest{ProductID: "pizza"},
+			wantErr: domain.ErrProductNotFound,
+		},
+		{
+			name:    "unavailable product",
+			req:     domain.QuoteRequest{ProductID: "cola"},
+			wantErr: domain.ErrProductUnavailable,
+		},
+		{
+			name:    "negative quantity",
+			req:     domain.QuoteRequest{ProductID: "burger", Quantity: -1, Selections: []domain.QuoteSelection{{AttributeID: "small"}}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name:    "missing required group",
+			req:     domain.QuoteRequest{ProductID: "burger"},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "unknown attribute",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "pickles"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "unavailable attribute",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "bbq"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "attribute quantity over its max",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "ketchup", Quantity: 4},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "negative attribute quantity",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "ketchup", Quantity: -1},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "group total over its max",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "ketchup", Quantity: 2},
+				{AttributeID: "mayo"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "attribute selected twice",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "small"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name:    "attribute in several groups without group_id",
+			req:     domain.QuoteRequest{ProductID: "combo", Selections: []domain.QuoteSelection{{AttributeID: "cheese"}}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "group not offered for the product",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "cheese", GroupID: "toppings"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+		{
+			name: "attribute not offered for the product",
+			req: domain.QuoteRequest{ProductID: "burger", Selections: []domain.QuoteSelection{
+				{AttributeID: "small"},
+				{AttributeID: "cheese"},
+			}},
+			wantErr: domain.ErrInvalidQuote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			menu := quoteTestMenu()
+
+			quote, err := calculateQuote(menu, tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("calculateQuote() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("calculateQuote() error = %v", err)
+			}
+
+			if len(quote.Lines) != tt.wantLines {
+				t.Errorf("lines = %d, want %d", len(quote.Lines), tt.wantLines)
+			}
+			if quote.UnitPrice != tt.wantUnit {
+				t.Errorf("unit price = %v, want %v", quote.UnitPrice, tt.wantUnit)
+			}
+			if quote.Total != tt.wantTotal {
+				t.Errorf("total = %v, want %v", quote.Total, tt.wantTotal)
+			}
+			if quote.MenuID != menu.ID || quote.ProductID != tt.req.ProductID {
+				t.Errorf("quote is for %s/%s, want %s/%s", quote.MenuID.Hex(), quote.ProductID, menu.ID.Hex(), tt.req.ProductID)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+const (
+	// restoreLease is how long a claimed restore is held by one scheduler
+	restoreLease = time.Minute
+
+	// restoreRetryDelay is how long a restore that failed to fire waits
+	// before it is retried
+	restoreRetryDelay = time.Minute
+)
+
+// errRestorePending is returned when a restore is due before its temporary
+// status change was applied by the worker.
+var errRestorePending = errors.New("temporary status change is not applied yet")
+
+// RunScheduledRestores publishes the reverting status change of every restore
+// due at now and returns how many were published. Each restore is claimed
+// before it fires, so it fires on one instance only. A restore that fails to
+// fire is retried after restoreRetryDelay and does not hold up the others.
+func (s *ProductService) RunScheduledRestores(ctx context.Context, now time.Time) (int, error) {
+	fired := 0
+	for {
+		restore, err := s.restoreRepo.ClaimDue(ctx, now, restoreLease)
+		if err != nil {
+			return fired, err
+		}
+		if restore == nil {
+			return fired, nil
+		}
+
+		// the event is queued only if the restore is completed with it
+		var state string
+		err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			state, err = s.fireRestore(ctx, restore)
+			if err != nil {
+				return err
+			}
+			return s.restoreRepo.Complete(ctx, restore.ID, state)
+		})
+		if err != nil {
+			if errors.Is(err, errRestorePending) {
+				s.logger.Infow("scheduled restore waits for its status change", "restore_id", restore.ID.Hex(), "product_id", restore.ProductID, "sequence", restore.Sequence)
+			} else {
+				s.logger.Errorw("failed to fire scheduled restore", "restore_id", restore.ID.Hex(), "product_id", restore.ProductID, "error", err)
+			}
+			// nothing was published, let a later run retry it, or the claim
+			// expire if even that fails
+			if releaseErr := s.restoreRepo.Release(context.WithoutCancel(ctx), restore.ID, now.Add(restoreRetryDelay)); releaseErr != nil {
+				s.logger.Errorw("failed to release scheduled restore", "restore_id", restore.ID.Hex(), "error", releaseErr)
+			}
+			continue
+		}
+
+		if state == domain.RestoreDone {
+			fired++
+		}
+	}
+}
+
+// fireRestore publishes the status change of a claimed restore and returns
+// the state to complete it with.
+func (s *ProductService) fireRestore(ctx context.Context, restore *domain.ScheduledRestore) (string, error) {
+	menu, err := s.findProductMenu(ctx, restore.RestaurantID, restore.ProductID)
+	if err != nil {
+		if errors.Is(err, domain.ErrMenuNotFound) || errors.Is(err, domain.ErrProductNotFound) {
+			s.logger.Warnw("skipping restore of a missing product", "restaurant_id", restore.RestaurantID, "product_id", restore.ProductID)
+			return domain.RestoreSkipped, nil
+		}
+		return "", err
+	}
+
+	current, err := s.productStatus(ctx, menu, restore.BranchID, restore.ProductID)
+	if err != nil {
+		if errors.Is(err, domain.ErrBranchNotFound) {
+			s.logger.Warnw("skipping restore in a missing branch", "restaurant_id", restore.RestaurantID, "branch_id", restore.BranchID, "product_id", restore.ProductID)
+			return domain.RestoreSkipped, nil
+		}
+		return "", err
+	}
+
+	changed := current != restore.Status
+	// restores scheduled before they carried a sequence compare statuses
+	if restore.Sequence != 0 {
+		changed, err = s.restoreSuperseded(ctx, restore)
+		if err != nil {
+			return "", err
+		}
+	}
+	if changed {
+		s.logger.Infow("skipping restore of a product changed since", "restaurant_id", restore.RestaurantID, "branch_id", restore.BranchID, "product_id", restore.ProductID, "status", current)
+		return domain.RestoreSkipped, nil
+	}
+
+	sequence, err := s.sequenceRepo.Next(ctx, menu.RestaurantID, domain.EntityProduct, restore.ProductID)
+	if err != nil {
+		return "", err
+	}
+
+	event := domain.ProductStatusEvent{
+		EventType:    domain.EventProductStatusChanged,
+		Sequence:     sequence,
+		RestaurantID: menu.RestaurantID,
+		MenuID:       menu.ID.Hex(),
+		ProductID:    restore.ProductID,
+		BranchID:     restore.BranchID,
+		OldStatus:    current,
+		NewStatus:    restore.RestoreStatus,
+		Reason:       domain.ReasonAutoRestore,
+		UserID:       systemUserID,
+	}
+
+	if err := s.publishEvent(ctx, event); err != nil {
+		return "", err
+	}
+
+	return domain.RestoreDone, nil
+}
+
+// restoreSuperseded reports whether a change later than the temporary change
+// of the restore was applied to the product, or the temporary change was
+// dropped as stale. It returns errRestorePending while the temporary change
+// has not reached the worker, the restore must not be skipped for a status
+// that is about to change.
+func (s *ProductService) restoreSuperseded(ctx context.Context, restore *domain.ScheduledRestore) (bool, error) {
+	sequence, err := s.sequenceRepo.Get(ctx, restore.RestaurantID, domain.EntityProduct, restore.ProductID)
+	if err != nil {
+		return false, err
+	}
+
+	applied := sequence.AppliedTo(restore.BranchID)
+	switch {
+	case applied > restore.Sequence:
+		return true, nil
+	case applied == restore.Sequence:
+		return false, nil
+	case restore.BranchID == "" && sequence.AppliedMax >= restore.Sequence:
+		// a later branch change was applied first, the worker rejects the
+		// restaurant change when it arrives
+		return true, nil
+	default:
+		return false, errRestorePending
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+)
+
+// sequenceGetter returns the same status sequence for every product.
+type sequenceGetter struct {
+	repo.StatusSequenceRepository
+	sequence domain.StatusSequence
+}
+
+func (r sequenceGetter) Get(ctx context.Context, restaurantID, entityType, entityID string) (*domain.StatusSequence, error) {
+	sequence := r.sequence
+	return &sequence, nil
+}
+
+func TestRestoreSuperseded(t *testing.T) {
+	tests := []struct {
+		name     string
+		branchID string
+		sequence domain.StatusSequence
+		want     bool
+		wantErr  error
+	}{
+		{
+			name:     "temporary change applied last",
+			sequence: domain.StatusSequence{Applied: 5, AppliedMax: 5},
+		},
+		{
+			name:     "temporary change not applied yet",
+			sequence: domain.StatusSequence{Applied: 4, AppliedMax: 4},
+			wantErr:  errRestorePending,
+		},
+		{
+			name:     "later change applied",
+			sequence: domain.StatusSequence{Applied: 6, AppliedMax: 6},
+			want:     true,
+		},
+		{
+			name:     "later branch change rejects the temporary change",
+			sequence: domain.StatusSequence{Applied: 4, AppliedMax: 6, Branches: []domain.BranchSequence{{BranchID: "a", Applied: 6}}},
+			want:     true,
+		},
+		{
+			name:     "later branch change after the temporary change",
+			sequence: domain.StatusSequence{Applied: 5, AppliedMax: 6, Branches: []domain.BranchSequence{{BranchID: "a", Applied: 6}}},
+		},
+		{
+			name:     "branch change applied last",
+			branchID: "a",
+			sequence: domain.StatusSequence{Applied: 3, AppliedMax: 5, Branches: []domain.BranchSequence{{BranchID: "a", Applied: 5}}},
+		},
+		{
+			name:     "branch change not applied yet",
+			branchID: "a",
+			sequence: domain.StatusSequence{Applied: 3, AppliedMax: 6, Branches: []domain.BranchSequence{{BranchID: "b", Applied: 6}}},
+			wantErr:  errRestorePending,
+		},
+		{
+			name:     "later branch change applied",
+			branchID: "a",
+			sequence: domain.StatusSequence{Applied: 3, AppliedMax: 7, Branches: []domain.BranchSequence{{BranchID: "a", Applied: 7}}},
+			want:     true,
+		},
+		{
+			name:     "later restaurant change applied",
+			branchID: "a",
+			sequence: domain.StatusSequence{Applied: 6, AppliedMax: 6, Branches: []domain.BranchSequence{}},
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ProductService{sequenceRepo: sequenceGetter{sequence: tt.sequence}}
+			restore := &domain.ScheduledRestore{RestaurantID: "r", BranchID: tt.branchID, ProductID: "1001", Sequence: 5}
+
+			got, err := s.restoreSuperseded(context.Background(), restore)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("restoreSuperseded() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("restoreSuperseded() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,542 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"github.com/Beka01247/kwaaka-tz/internal/repo"
+	"github.com/Beka01247/kwaaka-tz/internal/store/mongo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// webhookBatchSize is the most deliveries attempted per dispatcher run
+	webhookBatchSize = 100
+
+	// webhookRetryBase is the delay after the first failed attempt of a
+	// delivery, it doubles after every further one up to webhookRetryMax
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+
+	// webhookLeaseMargin is added to the request timeout to get how long a
+	// claimed delivery is held by one dispatcher
+	webhookLeaseMargin = 30 * time.Second
+
+	// webhookMinSecretLength keeps signatures from being guessed
+	webhookMinSecretLength = 16
+)
+
+// errWebhookAddress is returned when a webhook URL resolves to an address
+// that is not on the public internet.
+var errWebhookAddress = errors.New("webhook address is not public")
+
+// webhookBlockedNets are non-public ranges not covered by the net.IP checks
+// in publicAddress: "this network", carrier-grade NAT and benchmarking.
+var webhookBlockedNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+// WebhookService manages webhook subscriptions of restaurants and delivers
+// queued events to them. Events are queued by the services making the
+// changes, in the same transaction, and delivered at least once: receivers
+// should deduplicate by event ID.
+type WebhookService struct {
+	webhookRepo  repo.WebhookRepository
+	deliveryRepo repo.WebhookDeliveryRepository
+	auditLogRepo repo.AuditLogRepository
+	storage      *mongo.Storage
+	client       *http.Client
+	maxAttempts  int
+	concurrency  int
+	logger       *zap.SugaredLogger
+}
+
+func NewWebhookService(
+	webhookRepo repo.WebhookRepository,
+	deliveryRepo repo.WebhookDeliveryRepository,
+	auditLogRepo repo.AuditLogRepository,
+	storage *mongo.Storage,
+	timeout time.Duration,
+	maxAttempts int,
+	concurrency int,
+	logger *zap.SugaredLogger,
+) *WebhookService {
+	return &WebhookService{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		auditLogRepo: auditLogRepo,
+		storage:      storage,
+		client:       newWebhookClient(timeout),
+		maxAttempts:  maxAttempts,
+		concurrency:  max(concurrency, 1),
+		logger:       logger,
+	}
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook *domain.Webhook, userID string) error {
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	err := s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+			return err
+		}
+
+		entry := webhookAuditEntry(webhook, domain.WebhookActionCreated, userID)
+		entry.After = webhookSummary(webhook)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("webhook created", "restaurant_id", webhook.RestaurantID, "webhook_id", webhook.ID.Hex(), "url", webhook.URL)
+
+	return nil
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, restaurantID string, id primitive.ObjectID) (*domain.Webhook, error) {
+	return s.webhookRepo.GetByID(ctx, restaurantID, id)
+}
+
+func (s *WebhookService) ListWebhooks(ctx context.Context, restaurantID string) ([]domain.Webhook, error) {
+	return s.webhookRepo.ListByRestaurantID(ctx, restaurantID)
+}
+
+// UpdateWebhook replaces the URL, event types and active flag of the webhook.
+// An empty secret keeps the current one. Queued deliveries are sent with the
+// new URL and secret.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, webhook *domain.Webhook, userID string) error {
+	current, err := s.webhookRepo.GetByID(ctx, webhook.RestaurantID, webhook.ID)
+	if err != nil {
+		return err
+	}
+
+	if webhook.Secret == "" {
+		webhook.Secret = current.Secret
+	}
+
+	if err := validateWebhook(webhook); err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+			return err
+		}
+
+		entry := webhookAuditEntry(webhook, domain.WebhookActionUpdated, userID)
+		entry.Before = webhookSummary(current)
+		entry.After = webhookSummary(webhook)
+		entry.After["secret_changed"] = webhook.Secret != current.Secret
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+	webhook.CreatedAt = current.CreatedAt
+
+	s.logger.Infow("webhook updated", "restaurant_id", webhook.RestaurantID, "webhook_id", webhook.ID.Hex(), "url", webhook.URL, "active", webhook.Active)
+
+	return nil
+}
+
+// DeleteWebhook removes the webhook. Its queued deliveries fail on their next
+// attempt, the delivery log is kept until it expires.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, restaurantID string, id primitive.ObjectID, userID string) error {
+	webhook, err := s.webhookRepo.GetByID(ctx, restaurantID, id)
+	if err != nil {
+		return err
+	}
+
+	err = s.storage.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.webhookRepo.Delete(ctx, restaurantID, id); err != nil {
+			return err
+		}
+
+		entry := webhookAuditEntry(webhook, domain.WebhookActionDeleted, userID)
+		entry.Before = webhookSummary(webhook)
+		return writeAuditLog(ctx, s.auditLogRepo, s.logger, entry)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Infow("webhook deleted", "restaurant_id", restaurantID, "webhook_id", id.Hex())
+
+	return nil
+}
+
+// ListDeliveries returns a page of the webhook's delivery log, newest first.
+// cursor is the NextCursor of the previous page, empty for the first one.
+func (s *WebhookService) ListDeliveries(ctx context.Context, restaurantID string, filter domain.WebhookDeliveryFilter, cursor string) (*domain.WebhookDeliveryPage, error) {
+	if _, err := s.webhookRepo.GetByID(ctx, restaurantID, filter.WebhookID); err != nil {
+		return nil, err
+	}
+
+	if cursor != "" {
+		timestamp, id, err := decodeAuditCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.AfterTimestamp = &timestamp
+		filter.AfterID = id
+	}
+
+	// one extra delivery tells whether there is a next page
+	limit := filter.Limit
+	filter.Limit++
+
+	deliveries, err := s.deliveryRepo.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.WebhookDeliveryPage{Items: deliveries}
+	if len(deliveries) > limit {
+		page.Items = deliveries[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeAuditCursor(last.CreatedAt, last.ID)
+	}
+
+	return page, nil
+}
+
+// DeliverPending attempts the deliveries due at now and returns how many were
+// delivered. Up to concurrency deliveries are sent at once, so a slow or
+// unreachable receiver doesn't hold up the whole run. A failed attempt is
+// scheduled again with exponential backoff until the delivery runs out of
+// attempts. The run stops at the first storage error.
+func (s *WebhookService) DeliverPending(ctx context.Context, now time.Time) (int, error) {
+	var (
+		mu        sync.Mutex
+		claims    int
+		delivered int
+		runErr    error
+		wg        sync.WaitGroup
+	)
+
+	// next claims the next due delivery while the run has claims left and
+	// nothing has failed
+	next := func() (*domain.WebhookDelivery, error) {
+		mu.Lock()
+		if claims >= webhookBatchSize || runErr != nil {
+			mu.Unlock()
+			return nil, nil
+		}
+		claims++
+		mu.Unlock()
+
+		return s.deliveryRepo.ClaimNext(ctx, now, s.client.Timeout+webhookLeaseMargin)
+	}
+
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				delivery, err := next()
+				if err == nil && delivery == nil {
+					return
+				}
+
+				ok := false
+				if err == nil {
+					ok, err = s.attempt(ctx, delivery)
+				}
+
+				mu.Lock()
+				if err != nil && runErr == nil {
+					runErr = err
+				}
+				if ok {
+					delivered++
+				}
+				mu.Unlock()
+
+				if err != nil {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return delivered, runErr
+}
+
+// attempt sends a claimed delivery and records the outcome. It reports
+// whether the delivery succeeded.
+func (s *WebhookService) attempt(ctx context.Context, delivery *domain.WebhookDelivery) (bool, error) {
+	start := time.Now()
+	attempt := domain.WebhookAttempt{At: start}
+
+	// deliveries of deleted or disabled webhooks are not retried
+	final := false
+	webhook, err := s.webhookRepo.GetByID(ctx, delivery.RestaurantID, delivery.WebhookID)
+	switch {
+	case errors.Is(err, domain.ErrWebhookNotFound):
+		attempt.Error = "webhook was deleted"
+		final = true
+	case err != nil:
+		return false, err
+	case !webhook.Active:
+		attempt.Error = "webhook is disabled"
+		final = true
+	default:
+		attempt.URL = webhook.URL
+		attempt.StatusCode, err = s.send(ctx, webhook, delivery)
+		attempt.DurationMs = time.Since(start).Milliseconds()
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+	}
+
+	delivery.Attempts++
+	delivery.LastError = attempt.Error
+	switch {
+	case attempt.Error == "":
+		deliveredAt := time.Now()
+		delivery.State = domain.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &deliveredAt
+	case final || delivery.Attempts >= s.maxAttempts:
+		delivery.State = domain.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+	}
+
+	// the outcome is recorded even when stopping, the request was made
+	if err := s.deliveryRepo.RecordAttempt(context.WithoutCancel(ctx), delivery, attempt); err != nil {
+		return false, err
+	}
+
+	switch delivery.State {
+	case domain.WebhookDeliveryDelivered:
+		s.logger.Infow("webhook delivered", "delivery_id", delivery.ID.Hex(), "webhook_id", delivery.WebhookID.Hex(), "event_type", delivery.EventType, "attempts", delivery.Attempts)
+	case domain.WebhookDeliveryFailed:
+		s.logger.Warnw("webhook delivery failed", "delivery_id", delivery.ID.Hex(), "webhook_id", delivery.WebhookID.Hex(), "event_type", delivery.EventType, "attempts", delivery.Attempts, "error", attempt.Error)
+	default:
+		s.logger.Infow("webhook delivery will be retried", "delivery_id", delivery.ID.Hex(), "webhook_id", delivery.WebhookID.Hex(), "attempts", delivery.Attempts, "next_attempt_at", delivery.NextAttemptAt, "error", attempt.Error)
+	}
+
+	return delivery.State == domain.WebhookDeliveryDelivered, nil
+}
+
+// send posts the payload of the delivery to the webhook and returns the
+// response status. Statuses other than 2xx are errors.
+func (s *WebhookService) send(ctx context.Context, webhook *domain.Webhook, delivery *domain.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Id", delivery.ID.Hex())
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// the error names the address, don't reveal what internal hosts
+		// resolve to
+		if errors.Is(err, errWebhookAddress) {
+			return 0, errWebhookAddress
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// only the status is kept, the body of a failed response could be
+	// anything the receiver returns
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// newWebhookClient returns the client deliveries are sent with. It connects
+// to public addresses only, checked after the host is resolved so a DNS name
+// can't point it at internal services, and does not follow redirects.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(net.ParseIP(host)) {
+				return errWebhookAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the receiver
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddress reports whether ip is on the public internet, so not a
+// loopback, private, link-local, multicast or other reserved address.
+func publicAddress(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, blocked := range webhookBlockedNets {
+		if blocked.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return ipNet
+}
+
+// signWebhook returns the hex HMAC-SHA256 of the timestamp and the payload
+// joined by a dot, keyed with the webhook secret. Signing the timestamp lets
+// receivers reject replayed requests.
+func signWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the delay before the next attempt of a delivery that
+// failed attempts times.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// queueWebhookEvent stores a delivery of the event for every webhook of the
+// restaurant subscribed to it. Called with a transaction context, the event
+// is delivered only if the transaction commits.
+func queueWebhookEvent(ctx context.Context, webhookRepo repo.WebhookRepository, deliveryRepo repo.WebhookDeliveryRepository, event domain.WebhookEvent) error {
+	// events queued before restaurants were tracked have no subscribers
+	if event.RestaurantID == "" {
+		return nil
+	}
+
+	webhooks, err := webhookRepo.ListSubscribed(ctx, event.RestaurantID, event.Type)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	event.ID = primitive.NewObjectID().Hex()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	for _, webhook := range webhooks {
+		delivery := &domain.WebhookDelivery{
+			WebhookID:    webhook.ID,
+			RestaurantID: event.RestaurantID,
+			EventID:      event.ID,
+			EventType:    event.Type,
+			Payload:      payload,
+		}
+		if err := deliveryRepo.Create(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateWebhook(webhook *domain.Webhook) error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", domain.ErrInvalidWebhook)
+	}
+
+	// names are checked again when they are resolved for each delivery
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if ip := net.ParseIP(host); (ip != nil && !publicAddress(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must point to a public address", domain.ErrInvalidWebhook)
+	}
+
+	for _, eventType := range webhook.EventTypes {
+		if !slices.Contains(domain.WebhookEventTypes, eventType) {
+			return fmt.Errorf("%w: unknown event type %q", domain.ErrInvalidWebhook, eventType)
+		}
+	}
+
+	if len(webhook.Secret) < webhookMinSecretLength {
+		return fmt.Errorf("%w: secret must be at least %d characters", domain.ErrInvalidWebhook, webhookMinSecretLength)
+	}
+
+	return nil
+}
+
+func webhookAuditEntry(webhook *domain.Webhook, action, userID string) *domain.AuditLogEntry {
+	return &domain.AuditLogEntry{
+		Actor:        userID,
+		Action:       action,
+		EntityType:   domain.EntityWebhook,
+		EntityID:     webhook.ID.Hex(),
+		RestaurantID: webhook.RestaurantID,
+	}
+}
+
+// webhookSummary is the snapshot of a webhook in the audit log, without its
+// secret.
+func webhookSummary(webhook *domain.Webhook) bson.M {
+	return bson.M{
+		"url":         webhook.URL,
+		"event_types": webhook.EventTypes,
+		"active":      webhook.Active,
+	}
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+)
+
+func TestSignWebhook(t *testing.T) {
+	// computed with: printf '%s' '1700000000.{"event":"product.created"}' |
+	// openssl dgst -sha256 -hmac 'whsec_0123456789abcdef'
+	const want = "7981e91436b9025f528b3e3eacae4cfdf19f10715d2211a42ed7291a0f77d62f"
+
+	got := signWebhook("whsec_0123456789abcdef", "1700000000", []byte(`{"event":"product.created"}`))
+	if got != want {
+		t.Errorf("signWebhook() = %s, want %s", got, want)
+	}
+
+	// the timestamp is signed, so a replay with another one doesn't verify
+	if other := signWebhook("whsec_0123456789abcdef", "1700000001", []byte(`{"event":"product.created"}`)); other == want {
+		t.Error("signWebhook() does not depend on the timestamp")
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "8.8.8.8", want: true},
+		{ip: "2606:4700:4700::1111", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "127.1.2.3", want: false},
+		{ip: "::1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "::", want: false},
+		{ip: "0.1.2.3", want: false},
+		{ip: "10.0.0.1", want: false},
+		{ip: "172.16.5.4", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "198.18.0.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "224.0.0.1", want: false},
+		{ip: "ff02::1", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "::ffff:10.0.0.1", want: false},
+		{ip: "::ffff:169.254.169.254", want: false},
+		{ip: "::ffff:93.184.216.34", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := publicAddress(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("publicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+
+	if publicAddress(nil) {
+		t.Error("publicAddress(nil) = true, want false")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 0, want: 30 * time.Second},
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 7, want: 32 * time.Minute},
+		{attempts: 8, want: time.Hour},
+		{attempts: 50, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	const secret = "whsec_0123456789abcdef"
+
+	tests := []struct {
+		name    string
+		webhook domain.Webhook
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			webhook: domain.Webhook{URL: "https://example.com/hooks", EventTypes: []string{domain.EventProductStatusChanged}, Secret: secret},
+		},
+		{
+			name:    "all events",
+			webhook: domain.Webhook{URL: "http://93.184.216.34:8080/hooks", Secret: secret},
+		},
+		{
+			name:    "relative url",
+			webhook: domain.Webhook{URL: "/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			webhook: domain.Webhook{URL: "ftp://example.com/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "loopback",
+			webhook: domain.Webhook{URL: "http://127.0.0.1/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "ipv6 loopback",
+			webhook: domain.Webhook{URL: "http://[::1]:8080/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "ipv4-mapped loopback",
+			webhook: domain.Webhook{URL: "http://[::ffff:127.0.0.1]/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "cloud metadata",
+			webhook: domain.Webhook{URL: "http://169.254.169.254/latest/meta-data", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "private",
+			webhook: domain.Webhook{URL: "https://10.1.2.3/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "localhost",
+			webhook: domain.Webhook{URL: "http://LocalHost./hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "localhost subdomain",
+			webhook: domain.Webhook{URL: "http://api.localhost/hooks", Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "unknown event type",
+			webhook: domain.Webhook{URL: "https://example.com/hooks", EventTypes: []string{"product.eaten"}, Secret: secret},
+			wantErr: true,
+		},
+		{
+			name:    "short secret",
+			webhook: domain.Webhook{URL: "https://example.com/hooks", Secret: "short"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhook(&tt.webhook)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidWebhook) {
+					t.Errorf("validateWebhook() error = %v, want %v", err, domain.ErrInvalidWebhook)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("validateWebhook() error = %v", err)
+			}
+		})
+	}
+}
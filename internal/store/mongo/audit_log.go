@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditLogRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditLogRepository(db *mongo.Database) *AuditLogRepository {
+	return &AuditLogRepository{
+		collection: db.Collection("audit_log"),
+	}
+}
+
+func (r *AuditLogRepository) Create(ctx context.Context, entry *domain.AuditLogEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log entry: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AuditLogRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*domain.AuditLogEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var entry domain.AuditLogEntry
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrAuditLogEntryNotFound
+		}
+		return nil, fmt.Errorf("failed to get audit log entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// Find returns audit log entries matching the filter newest first. Entries
+// with the same timestamp are ordered by ID so pages don't overlap.
+func (r *AuditLogRepository) Find(ctx context.Context, filter domain.AuditLogFilter) ([]domain.AuditLogEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.EntityType != "" {
+		query["entity_type"] = filter.EntityType
+	}
+	if filter.EntityID != "" {
+		query["entity_id"] = filter.EntityID
+	}
+	if filter.RestaurantID != "" {
+		query["restaurant_id"] = filter.RestaurantID
+	}
+	if filter.Actor != "" {
+		query["actor"] = filter.Actor
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+
+	timestamp := bson.M{}
+	if filter.From != nil {
+		timestamp["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		timestamp["$lt"] = *filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+
+	if filter.AfterTimestamp != nil {
+		query["$or"] = bson.A{
+			bson.M{"timestamp": bson.M{"$lt": *filter.AfterTimestamp}},
+			bson.M{"timestamp": *filter.AfterTimestamp, "_id": bson.M{"$lt": filter.AfterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log entries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []domain.AuditLogEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode audit log entries: %w", err)
+	}
+
+	return entries, nil
+}
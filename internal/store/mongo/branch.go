@@ -0,0 +1,259 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BranchRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBranchRepository(db *mongo.Database) *BranchRepository {
+	return &BranchRepository{
+		collection: db.Collection("branches"),
+	}
+}
+
+func (r *BranchRepository) Create(ctx context.Context, branch *domain.Branch) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if branch.Products == nil {
+		branch.Products = []domain.ProductOverride{}
+	}
+	if branch.Attributes == nil {
+		branch.Attributes = []domain.AttributeOverride{}
+	}
+	branch.CreatedAt = time.Now()
+	branch.UpdatedAt = time.Now()
+
+	if _, err := r.collection.InsertOne(ctx, branch); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrBranchExists
+		}
+		return fmt.Errorf("failed to create branch: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BranchRepository) GetByID(ctx context.Context, restaurantID, branchID string) (*domain.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var branch domain.Branch
+	err := r.collection.FindOne(ctx, branchFilter(restaurantID, branchID)).Decode(&branch)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrBranchNotFound
+		}
+		return nil, fmt.Errorf("failed to get branch: %w", err)
+	}
+
+	return &branch, nil
+}
+
+func (r *BranchRepository) ListByRestaurantID(ctx context.Context, restaurantID string) ([]domain.Branch, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"restaurant_id": restaurantID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	branches := []domain.Branch{}
+	if err := cursor.All(ctx, &branches); err != nil {
+		return nil, fmt.Errorf("failed to decode branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+func (r *BranchRepository) Delete(ctx context.Context, restaurantID, branchID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, branchFilter(restaurantID, branchID))
+	if err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrBranchNotFound
+	}
+
+	return nil
+}
+
+func (r *BranchRepository) SetProductPrice(ctx context.Context, restaurantID, branchID, productID string, price *float64) error {
+	if price == nil {
+		return r.setOverride(ctx, restaurantID, branchID, "products", "product_id", productID, "price", nil)
+	}
+	return r.setOverride(ctx, restaurantID, branchID, "products", "product_id", productID, "price", *price)
+}
+
+func (r *BranchRepository) SetProductStatus(ctx context.Context, restaurantID, branchID, productID, status string) error {
+	if status == "" {
+		return r.setOverride(ctx, restaurantID, branchID, "products", "product_id", productID, "status", nil)
+	}
+	return r.setOverride(ctx, restaurantID, branchID, "products", "product_id", productID, "status", status)
+}
+
+func (r *BranchRepository) SetAttributePrice(ctx context.Context, restaurantID, branchID, attributeID string, price *float64) error {
+	if price == nil {
+		return r.setOverride(ctx, restaurantID, branchID, "attributes", "attribute_id", attributeID, "price", nil)
+	}
+	return r.setOverride(ctx, restaurantID, branchID, "attributes", "attribute_id", attributeID, "price", *price)
+}
+
+func (r *BranchRepository) SetAttributeStatus(ctx context.Context, restaurantID, branchID, attributeID, status string) error {
+	if status == "" {
+		return r.setOverride(ctx, restaurantID, branchID, "attributes", "attribute_id", attributeID, "status", nil)
+	}
+	return r.setOverride(ctx, restaurantID, branchID, "attributes", "attribute_id", attributeID, "status", status)
+}
+
+// ClearProductStatus removes the product's status override from every branch
+// of the restaurant.
+func (r *BranchRepository) ClearProductStatus(ctx context.Context, restaurantID, productID string) error {
+	return r.clearStatus(ctx, restaurantID, "products", "product_id", productID)
+}
+
+// ClearAttributeStatus removes the attribute's status override from every
+// branch of the restaurant.
+func (r *BranchRepository) ClearAttributeStatus(ctx context.Context, restaurantID, attributeID string) error {
+	return r.clearStatus(ctx, restaurantID, "attributes", "attribute_id", attributeID)
+}
+
+// setOverride sets field of the override of id in the given array of the
+// branch, adding the override if the branch has none yet. A nil value removes
+// the field.
+func (r *BranchRepository) setOverride(ctx context.Context, restaurantID, branchID, array, key, id, field string, value interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := branchFilter(restaurantID, branchID)
+	filter[array+"."+key] = id
+
+	if value == nil {
+		update := bson.M{
+			"$unset": bson.M{array + ".$." + field: ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+		result, err := r.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return fmt.Errorf("failed to remove branch override: %w", err)
+		}
+		if result.MatchedCount == 0 {
+			return r.exists(ctx, restaurantID, branchID)
+		}
+		return r.pullEmpty(ctx, branchFilter(restaurantID, branchID), array)
+	}
+
+	// the override may be added by a concurrent request between the two
+	// updates, so retry the positional update once
+	for attempt := 0; attempt < 2; attempt++ {
+		update := bson.M{
+			"$set": bson.M{
+				array + ".$." + field: value,
+				"updated_at":          time.Now(),
+			},
+		}
+		result, err := r.collection.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return fmt.Errorf("failed to update branch override: %w", err)
+		}
+		if result.MatchedCount > 0 {
+			return nil
+		}
+
+		pushFilter := branchFilter(restaurantID, branchID)
+		pushFilter[array+"."+key] = bson.M{"$ne": id}
+		push := bson.M{
+			"$push": bson.M{array: bson.M{key: id, field: value}},
+			"$set":  bson.M{"updated_at": time.Now()},
+		}
+		result, err = r.collection.UpdateOne(ctx, pushFilter, push)
+		if err != nil {
+			return fmt.Errorf("failed to add branch override: %w", err)
+		}
+		if result.MatchedCount > 0 {
+			return nil
+		}
+
+		if err := r.exists(ctx, restaurantID, branchID); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to set branch override: concurrent updates of %s %q", key, id)
+}
+
+func (r *BranchRepository) clearStatus(ctx context.Context, restaurantID, array, key, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"restaurant_id":   restaurantID,
+		array + "." + key: id,
+	}
+	update := bson.M{
+		"$unset": bson.M{array + ".$[override].status": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"override." + key: id}},
+	})
+
+	if _, err := r.collection.UpdateMany(ctx, filter, update, opts); err != nil {
+		return fmt.Errorf("failed to clear branch status overrides: %w", err)
+	}
+
+	return r.pullEmpty(ctx, bson.M{"restaurant_id": restaurantID}, array)
+}
+
+// pullEmpty removes overrides that no longer override anything.
+func (r *BranchRepository) pullEmpty(ctx context.Context, filter bson.M, array string) error {
+	update := bson.M{
+		"$pull": bson.M{array: bson.M{
+			"price":  bson.M{"$exists": false},
+			"status": bson.M{"$exists": false},
+		}},
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to remove empty branch overrides: %w", err)
+	}
+
+	return nil
+}
+
+func (r *BranchRepository) exists(ctx context.Context, restaurantID, branchID string) error {
+	count, err := r.collection.CountDocuments(ctx, branchFilter(restaurantID, branchID), options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("failed to find branch: %w", err)
+	}
+
+	if count == 0 {
+		return domain.ErrBranchNotFound
+	}
+
+	return nil
+}
+
+func branchFilter(restaurantID, branchID string) bson.M {
+	return bson.M{
+		"restaurant_id": restaurantID,
+		"id":            branchID,
+	}
+}
@@ -3,21 +3,27 @@ package mongo
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Beka01247/kwaaka-tz/internal/domain"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// MenuRepository stores menus in the menus collection and their products in
+// the products collection. Menus are assembled on read.
 type MenuRepository struct {
 	collection *mongo.Collection
+	products   *mongo.Collection
 }
 
 func NewMenuRepository(db *mongo.Database) *MenuRepository {
 	return &MenuRepository{
 		collection: db.Collection("menus"),
+		products:   db.Collection("products"),
 	}
 }
 
@@ -28,14 +34,19 @@ func (r *MenuRepository) Create(ctx context.Context, menu *domain.Menu) error {
 	if menu.ID.IsZero() {
 		menu.ID = primitive.NewObjectID()
 	}
+	menu.Version = 1
 	menu.CreatedAt = time.Now()
 	menu.UpdatedAt = time.Now()
 
-	_, err := r.collection.InsertOne(ctx, menu)
+	_, err := r.collection.InsertOne(ctx, menuDocument(menu))
 	if err != nil {
 		return fmt.Errorf("failed to create menu: %w", err)
 	}
 
+	if err := r.insertProducts(ctx, menu.ID, menu.RestaurantID, menu.Products); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -44,129 +55,442 @@ func (r *MenuRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*d
 	defer cancel()
 
 	var menu domain.Menu
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&menu)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}).Decode(&menu)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("menu not found")
+			return nil, domain.ErrMenuNotFound
 		}
 		return nil, fmt.Errorf("failed to get menu: %w", err)
 	}
 
+	if err := r.loadProducts(ctx, &menu); err != nil {
+		return nil, err
+	}
+
 	return &menu, nil
 }
 
+// GetDocumentByID returns the menu limited to the given fields, in their
+// stored (bson) names. The version is always included.
+func (r *MenuRepository) GetDocumentByID(ctx context.Context, id primitive.ObjectID, fields []string) (bson.M, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	projection := bson.M{"_id": 0, "version": 1}
+	withProducts := false
+	var productFields []string
+	for _, field := range fields {
+		if field == "products" {
+			withProducts = true
+			continue
+		}
+		if sub, ok := strings.CutPrefix(field, "products."); ok {
+			withProducts = true
+			productFields = append(productFields, sub)
+			continue
+		}
+		projection[field] = 1
+	}
+
+	var menu bson.M
+	opts := options.FindOne().SetProjection(projection)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrMenuNotFound
+		}
+		return nil, fmt.Errorf("failed to get menu: %w", err)
+	}
+
+	if withProducts {
+		products := []bson.M{}
+		if err := r.findProducts(ctx, bson.M{"menu_id": id}, productFields, 0, &products); err != nil {
+			return nil, fmt.Errorf("failed to get menu products: %w", err)
+		}
+		menu["products"] = products
+	}
+
+	return menu, nil
+}
+
 func (r *MenuRepository) GetByRestaurantID(ctx context.Context, restaurantID string) (*domain.Menu, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	// restaurants get a new menu on every import, the latest one is current
 	var menu domain.Menu
-	err := r.collection.FindOne(ctx, bson.M{"restaurant_id": restaurantID}).Decode(&menu)
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"restaurant_id": restaurantID, "deleted_at": nil}, opts).Decode(&menu)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("menu not found")
+			return nil, domain.ErrMenuNotFound
 		}
 		return nil, fmt.Errorf("failed to get menu: %w", err)
 	}
 
+	if err := r.loadProducts(ctx, &menu); err != nil {
+		return nil, err
+	}
+
 	return &menu, nil
 }
 
+// Update replaces the menu and its products if it is still at menu.Version
+// and bumps the version.
 func (r *MenuRepository) Update(ctx context.Context, menu *domain.Menu) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
+	version := menu.Version
+	menu.Version = version + 1
 	menu.UpdatedAt = time.Now()
 
-	filter := bson.M{"_id": menu.ID}
+	filter := bson.M{
+		"_id":        menu.ID,
+		"version":    versionMatch(version),
+		"deleted_at": nil,
+	}
 	update := bson.M{
-		"$set": menu,
+		"$set": menuDocument(menu),
 	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
+		menu.Version = version
 		return fmt.Errorf("failed to update menu: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("menu not found")
+		menu.Version = version
+		return r.missError(ctx, menu.ID, version, domain.ErrVersionConflict)
+	}
+
+	if _, err := r.products.DeleteMany(ctx, bson.M{"menu_id": menu.ID}); err != nil {
+		return fmt.Errorf("failed to replace menu products: %w", err)
+	}
+
+	if err := r.insertProducts(ctx, menu.ID, menu.RestaurantID, menu.Products); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// menuDocument returns the menu as stored in the menus collection, without
+// its products.
+func menuDocument(menu *domain.Menu) *domain.Menu {
+	doc := *menu
+	doc.Products = nil
+	return &doc
+}
+
+func (r *MenuRepository) AddAttributeGroup(ctx context.Context, menuID primitive.ObjectID, group *domain.AttributeGroup, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":                  menuID,
+		"version":              versionMatch(version),
+		"deleted_at":           nil,
+		"attributes_groups.id": bson.M{"$ne": group.ID},
+	}
+	if len(group.Attributes) > 0 {
+		filter["attributes.id"] = bson.M{"$all": group.Attributes}
+	}
+	update := bson.M{
+		"$inc":  bson.M{"version": 1},
+		"$push": bson.M{"attributes_groups": group},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to add attribute group: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return r.missError(ctx, menuID, version, domain.ErrAttributeGroupExists)
 	}
 
 	return nil
 }
 
-func (r *MenuRepository) UpdateProductStatus(ctx context.Context, menuID primitive.ObjectID, productID string, status string) error {
+func (r *MenuRepository) ReplaceAttributeGroup(ctx context.Context, menuID primitive.ObjectID, group *domain.AttributeGroup, version int64) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	filter := bson.M{
-		"_id":         menuID,
-		"products.id": productID,
+		"_id":                  menuID,
+		"version":              versionMatch(version),
+		"deleted_at":           nil,
+		"attributes_groups.id": group.ID,
+	}
+	if len(group.Attributes) > 0 {
+		filter["attributes.id"] = bson.M{"$all": group.Attributes}
 	}
 	update := bson.M{
+		"$inc": bson.M{"version": 1},
 		"$set": bson.M{
-			"products.$.status": status,
-			"updated_at":        time.Now(),
+			"attributes_groups.$[g]": group,
+			"updated_at":             time.Now(),
 		},
 	}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"g.id": group.ID}},
+	})
+
+	result, err := r.collection.UpdateOne(ctx, filter, update, opts)
+	if err != nil {
+		return fmt.Errorf("failed to replace attribute group: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return r.missError(ctx, menuID, version, domain.ErrAttributeGroupNotFound)
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) RemoveAttributeGroup(ctx context.Context, menuID primitive.ObjectID, groupID string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// the group must not be attached to any product at the time of removal
+	linked, err := r.products.CountDocuments(ctx, bson.M{"menu_id": menuID, "attributes": groupID})
+	if err != nil {
+		return fmt.Errorf("failed to check attribute group usage: %w", err)
+	}
+	if linked > 0 {
+		return domain.ErrAttributeGroupInUse
+	}
+
+	filter := bson.M{
+		"_id":                  menuID,
+		"version":              versionMatch(version),
+		"deleted_at":           nil,
+		"attributes_groups.id": groupID,
+	}
+	update := bson.M{
+		"$inc":  bson.M{"version": 1},
+		"$pull": bson.M{"attributes_groups": bson.M{"id": groupID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update product status: %w", err)
+		return fmt.Errorf("failed to remove attribute group: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("menu or product not found")
+		return r.missError(ctx, menuID, version, domain.ErrAttributeGroupNotFound)
 	}
 
 	return nil
 }
 
-func (r *MenuRepository) FindMenuByProductID(ctx context.Context, productID string) (*domain.Menu, error) {
+func (r *MenuRepository) AddAttribute(ctx context.Context, menuID primitive.ObjectID, attribute *domain.Attribute, version int64) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var menu domain.Menu
-	filter := bson.M{"products.id": productID}
-	err := r.collection.FindOne(ctx, filter).Decode(&menu)
+	filter := bson.M{
+		"_id":           menuID,
+		"version":       versionMatch(version),
+		"deleted_at":    nil,
+		"attributes.id": bson.M{"$ne": attribute.ID},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"version": 1},
+		"$push": bson.M{"attributes": attribute},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, fmt.Errorf("menu with product not found")
-		}
-		return nil, fmt.Errorf("failed to find menu by product: %w", err)
+		return fmt.Errorf("failed to add attribute: %w", err)
 	}
 
-	return &menu, nil
+	if result.MatchedCount == 0 {
+		return r.missError(ctx, menuID, version, domain.ErrAttributeExists)
+	}
+
+	return nil
 }
 
-func (r *MenuRepository) UpdateProductStatusByProductID(ctx context.Context, productID string, status string) error {
+func (r *MenuRepository) ReplaceAttribute(ctx context.Context, menuID primitive.ObjectID, attribute *domain.Attribute, version int64) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"products.id": productID}
+	filter := bson.M{
+		"_id":           menuID,
+		"version":       versionMatch(version),
+		"deleted_at":    nil,
+		"attributes.id": attribute.ID,
+	}
 	update := bson.M{
+		"$inc": bson.M{"version": 1},
 		"$set": bson.M{
-			"products.$.status": status,
-			"updated_at":        time.Now(),
+			"attributes.$": attribute,
+			"updated_at":   time.Now(),
 		},
 	}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return fmt.Errorf("failed to update product status: %w", err)
+		return fmt.Errorf("failed to replace attribute: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("product not found")
+		return r.missError(ctx, menuID, version, domain.ErrAttributeNotFound)
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) RemoveAttribute(ctx context.Context, menuID primitive.ObjectID, attributeID string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// the attribute must not be referenced by any group at the time of removal
+	filter := bson.M{
+		"_id":                          menuID,
+		"version":                      versionMatch(version),
+		"deleted_at":                   nil,
+		"attributes.id":                attributeID,
+		"attributes_groups.attributes": bson.M{"$ne": attributeID},
+	}
+	update := bson.M{
+		"$inc":  bson.M{"version": 1},
+		"$pull": bson.M{"attributes": bson.M{"id": attributeID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to remove attribute: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return r.missError(ctx, menuID, version, domain.ErrAttributeInUse)
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) UpdateAttributeStatus(ctx context.Context, menuID primitive.ObjectID, attributeID string, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":           menuID,
+		"deleted_at":    nil,
+		"attributes.id": attributeID,
+	}
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		"$set": bson.M{
+			"attributes.$.status": status,
+			"updated_at":          time.Now(),
+		},
 	}
 
-	if result.ModifiedCount == 0 {
-		// Product found but status didn't change (already at that status)
-		return nil
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update attribute status: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrAttributeNotFound
 	}
 
 	return nil
 }
 
+// missError tells a missing menu and a stale version apart from a filter miss
+// on the menu contents.
+func (r *MenuRepository) missError(ctx context.Context, menuID primitive.ObjectID, version int64, contentErr error) error {
+	var menu struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	err := r.collection.FindOne(ctx, bson.M{"_id": menuID, "deleted_at": nil}, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return domain.ErrMenuNotFound
+		}
+		return fmt.Errorf("failed to check menu: %w", err)
+	}
+
+	if menu.Version != version {
+		return domain.ErrVersionConflict
+	}
+
+	return contentErr
+}
+
+// bumpVersion increments the version of the menu if it is still at version
+// and matches filter, and returns the menu's restaurant ID. Product writes
+// bump the menu version before touching the products collection.
+func (r *MenuRepository) bumpVersion(ctx context.Context, menuID primitive.ObjectID, version int64, filter bson.M, contentErr error) (string, error) {
+	match := bson.M{
+		"_id":        menuID,
+		"version":    versionMatch(version),
+		"deleted_at": nil,
+	}
+	for key, value := range filter {
+		match[key] = value
+	}
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	var menu struct {
+		RestaurantID string `bson:"restaurant_id"`
+	}
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"restaurant_id": 1})
+	err := r.collection.FindOneAndUpdate(ctx, match, update, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", r.missError(ctx, menuID, version, contentErr)
+		}
+		return "", fmt.Errorf("failed to update menu version: %w", err)
+	}
+
+	return menu.RestaurantID, nil
+}
+
+// touch bumps the menu version after an unconditional write to its products
+// and returns the menu's restaurant ID.
+func (r *MenuRepository) touch(ctx context.Context, menuID primitive.ObjectID) (string, error) {
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	}
+
+	var menu struct {
+		RestaurantID string `bson:"restaurant_id"`
+	}
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"restaurant_id": 1})
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": menuID, "deleted_at": nil}, update, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", domain.ErrMenuNotFound
+		}
+		return "", fmt.Errorf("failed to update menu version: %w", err)
+	}
+
+	return menu.RestaurantID, nil
+}
+
+// versionMatch matches menus at the given version. Menus stored before
+// versioning have no version field and count as version 0.
+func versionMatch(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
 func (r *MenuRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -177,7 +501,126 @@ func (r *MenuRepository) Delete(ctx context.Context, id primitive.ObjectID) erro
 	}
 
 	if result.DeletedCount == 0 {
-		return fmt.Errorf("menu not found")
+		return domain.ErrMenuNotFound
+	}
+
+	if _, err := r.products.DeleteMany(ctx, bson.M{"menu_id": id}); err != nil {
+		return fmt.Errorf("failed to delete menu products: %w", err)
+	}
+
+	return nil
+}
+
+// SoftDelete marks the menu as deleted if it is still at version. The menu is
+// hidden from reads until it is restored or purged.
+func (r *MenuRepository) SoftDelete(ctx context.Context, id primitive.ObjectID, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        id,
+		"version":    versionMatch(version),
+		"deleted_at": nil,
+	}
+	update := bson.M{
+		"$inc": bson.M{"version": 1},
+		"$set": bson.M{
+			"deleted_at": time.Now(),
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to delete menu: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return r.missError(ctx, id, version, domain.ErrVersionConflict)
+	}
+
+	return nil
+}
+
+// Restore brings back a soft-deleted menu and returns it.
+func (r *MenuRepository) Restore(ctx context.Context, id primitive.ObjectID) (*domain.Menu, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        id,
+		"deleted_at": bson.M{"$ne": nil},
+	}
+	update := bson.M{
+		"$inc":   bson.M{"version": 1},
+		"$unset": bson.M{"deleted_at": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	var menu domain.Menu
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrMenuNotFound
+		}
+		return nil, fmt.Errorf("failed to restore menu: %w", err)
+	}
+
+	if err := r.loadProducts(ctx, &menu); err != nil {
+		return nil, err
+	}
+
+	return &menu, nil
+}
+
+// FindDeletedBefore returns up to limit menus soft-deleted before the given
+// time, without their products.
+func (r *MenuRepository) FindDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Menu, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"deleted_at": bson.M{"$lt": before}}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deleted_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find deleted menus: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	menus := []domain.Menu{}
+	if err := cursor.All(ctx, &menus); err != nil {
+		return nil, fmt.Errorf("failed to decode deleted menus: %w", err)
+	}
+
+	return menus, nil
+}
+
+// Purge permanently removes a menu soft-deleted before the given time along
+// with its products. A menu restored in the meantime is left alone.
+func (r *MenuRepository) Purge(ctx context.Context, id primitive.ObjectID, before time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":        id,
+		"deleted_at": bson.M{"$lt": before},
+	}
+
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to purge menu: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrMenuNotFound
+	}
+
+	if _, err := r.products.DeleteMany(ctx, bson.M{"menu_id": id}); err != nil {
+		return fmt.Errorf("failed to purge menu products: %w", err)
 	}
 
 	return nil
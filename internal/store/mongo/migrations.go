@@ -0,0 +1,160 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrateEmbeddedProducts moves products embedded in menu documents to the
+// products collection and returns the number of menus migrated. It can be run
+// repeatedly, menus without embedded products are skipped.
+func (s *Storage) MigrateEmbeddedProducts(ctx context.Context) (int, error) {
+	menus := s.database.Collection("menus")
+	products := s.database.Collection("products")
+
+	filter := bson.M{"products": bson.M{"$exists": true}}
+	opts := options.Find().SetProjection(bson.M{"restaurant_id": 1, "products": 1})
+	cursor, err := menus.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find menus with embedded products: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var menu struct {
+			ID           primitive.ObjectID `bson:"_id"`
+			RestaurantID string             `bson:"restaurant_id"`
+			Products     []domain.Product   `bson:"products"`
+		}
+		if err := cursor.Decode(&menu); err != nil {
+			return migrated, fmt.Errorf("failed to decode menu: %w", err)
+		}
+
+		// upserts keep the migration safe to resume after a partial run
+		models := make([]mongo.WriteModel, 0, len(menu.Products))
+		for _, product := range menu.Products {
+			doc := productDocument{
+				MenuID:       menu.ID,
+				RestaurantID: menu.RestaurantID,
+				Product:      product,
+			}
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"menu_id": menu.ID, "id": product.ID}).
+				SetReplacement(doc).
+				SetUpsert(true))
+		}
+		if len(models) > 0 {
+			if _, err := products.BulkWrite(ctx, models); err != nil {
+				return migrated, fmt.Errorf("failed to move products of menu %s: %w", menu.ID.Hex(), err)
+			}
+		}
+
+		update := bson.M{"$unset": bson.M{"products": ""}}
+		if _, err := menus.UpdateOne(ctx, bson.M{"_id": menu.ID}, update); err != nil {
+			return migrated, fmt.Errorf("failed to unset products of menu %s: %w", menu.ID.Hex(), err)
+		}
+
+		migrated++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to iterate menus: %w", err)
+	}
+
+	// indexes on embedded products are no longer used
+	for _, name := range []string{"products.id_1", "products_text"} {
+		if _, err := menus.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return migrated, fmt.Errorf("failed to drop menus index %s: %w", name, err)
+		}
+	}
+
+	return migrated, nil
+}
+
+// MigrateAttributeStatuses marks attributes stored before they had a status
+// as available and returns the number of menus updated.
+func (s *Storage) MigrateAttributeStatuses(ctx context.Context) (int64, error) {
+	filter := bson.M{"attributes": bson.M{"$elemMatch": bson.M{"status": bson.M{"$exists": false}}}}
+	update := bson.M{"$set": bson.M{"attributes.$[attribute].status": domain.AttributeStatusAvailable}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"attribute.status": bson.M{"$exists": false}}},
+	})
+
+	result, err := s.database.Collection("menus").UpdateMany(ctx, filter, update, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set attribute statuses: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// MigrateMenuAudit copies records of the menu_audit collection, which only
+// tracked menu deletions, into the audit log and drops it. Records keep their
+// IDs, so an interrupted migration can be run again. Returns the number of
+// records copied.
+func (s *Storage) MigrateMenuAudit(ctx context.Context) (int, error) {
+	source := s.database.Collection("menu_audit")
+	target := s.database.Collection("audit_log")
+
+	cursor, err := source.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read menu_audit: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var record struct {
+			ID           primitive.ObjectID `bson:"_id"`
+			MenuID       primitive.ObjectID `bson:"menu_id"`
+			RestaurantID string             `bson:"restaurant_id"`
+			Action       string             `bson:"action"`
+			UserID       string             `bson:"user_id"`
+			Timestamp    time.Time          `bson:"timestamp"`
+		}
+		if err := cursor.Decode(&record); err != nil {
+			return migrated, fmt.Errorf("failed to decode menu_audit record: %w", err)
+		}
+
+		entry := domain.AuditLogEntry{
+			ID:           record.ID,
+			Actor:        record.UserID,
+			Action:       record.Action,
+			EntityType:   domain.EntityMenu,
+			EntityID:     record.MenuID.Hex(),
+			RestaurantID: record.RestaurantID,
+			MenuID:       record.MenuID,
+			Timestamp:    record.Timestamp,
+		}
+		opts := options.Replace().SetUpsert(true)
+		if _, err := target.ReplaceOne(ctx, bson.M{"_id": entry.ID}, entry, opts); err != nil {
+			return migrated, fmt.Errorf("failed to copy menu_audit record %s: %w", record.ID.Hex(), err)
+		}
+		migrated++
+	}
+
+	if err := cursor.Err(); err != nil {
+		return migrated, fmt.Errorf("failed to read menu_audit: %w", err)
+	}
+
+	if err := source.Drop(ctx); err != nil {
+		return migrated, fmt.Errorf("failed to drop menu_audit: %w", err)
+	}
+
+	return migrated, nil
+}
+
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	// IndexNotFound, or NamespaceNotFound when there is no collection yet
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 27 || cmdErr.Code == 26)
+}
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OutboxRepository struct {
+	collection *mongo.Collection
+}
+
+func NewOutboxRepository(db *mongo.Database) *OutboxRepository {
+	return &OutboxRepository{
+		collection: db.Collection("outbox"),
+	}
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, message *domain.OutboxMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if message.ID.IsZero() {
+		message.ID = primitive.NewObjectID()
+	}
+	message.State = domain.OutboxPending
+	message.CreatedAt = time.Now()
+	message.AvailableAt = message.CreatedAt
+
+	_, err := r.collection.InsertOne(ctx, message)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox message: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimNext atomically claims the oldest pending message available at now
+// for lease and returns it, or nil when there is none. A message whose relay
+// died before marking it sent becomes available again when the lease ends.
+func (r *OutboxRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*domain.OutboxMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"state":        domain.OutboxPending,
+		"available_at": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"available_at": now.Add(lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var message domain.OutboxMessage
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim outbox message: %w", err)
+	}
+
+	return &message, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"state":   domain.OutboxSent,
+			"sent_at": time.Now(),
+		},
+		"$unset": bson.M{"last_error": ""},
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to mark outbox message sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed records why publishing the message failed. The message stays
+// claimed until its lease ends, so it is retried after that.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id primitive.ObjectID, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"last_error": reason}}
+
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to mark outbox message failed: %w", err)
+	}
+
+	return nil
+}
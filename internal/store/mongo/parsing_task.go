@@ -55,6 +55,37 @@ func (r *ParsingTaskRepository) GetByID(ctx context.Context, id primitive.Object
 	return &task, nil
 }
 
+// StartProcessing moves a queued or failed task to processing. A task left
+// processing by a worker that died is picked up again. Completed tasks are
+// never reopened, they return domain.ErrTaskAlreadyCompleted.
+func (r *ParsingTaskRepository) StartProcessing(ctx context.Context, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": bson.A{domain.StatusQueued, domain.StatusFailed, domain.StatusProcessing}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":     domain.StatusProcessing,
+			"updated_at": time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update parsing task status: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrTaskAlreadyCompleted
+	}
+
+	return nil
+}
+
+// UpdateStatus sets the status of a task that is not completed yet.
 func (r *ParsingTaskRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status domain.ParsingTaskStatus, errorMsg string) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
@@ -70,37 +101,41 @@ func (r *ParsingTaskRepository) UpdateStatus(ctx context.Context, id primitive.O
 		update["$set"].(bson.M)["error_message"] = errorMsg
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	filter := bson.M{"_id": id, "status": bson.M{"$ne": domain.StatusCompleted}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update parsing task status: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("parsing task not found")
+		return fmt.Errorf("parsing task not found or already completed")
 	}
 
 	return nil
 }
 
-func (r *ParsingTaskRepository) UpdateWithMenuID(ctx context.Context, id primitive.ObjectID, menuID primitive.ObjectID, status domain.ParsingTaskStatus) error {
+func (r *ParsingTaskRepository) UpdateWithMenuID(ctx context.Context, id primitive.ObjectID, menuID primitive.ObjectID, status domain.ParsingTaskStatus, carriedStatuses []domain.CarriedStatus) error {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	update := bson.M{
 		"$set": bson.M{
-			"menu_id":    menuID,
-			"status":     status,
-			"updated_at": time.Now(),
+			"menu_id":          menuID,
+			"status":           status,
+			"carried_statuses": carriedStatuses,
+			"updated_at":       time.Now(),
 		},
 	}
 
-	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	// a task is completed once, by the first of concurrent deliveries
+	filter := bson.M{"_id": id, "status": bson.M{"$ne": domain.StatusCompleted}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to update parsing task: %w", err)
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("parsing task not found")
+		return domain.ErrTaskAlreadyCompleted
 	}
 
 	return nil
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ProcessedEventRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProcessedEventRepository(db *mongo.Database) *ProcessedEventRepository {
+	return &ProcessedEventRepository{
+		collection: db.Collection("processed_events"),
+	}
+}
+
+// Create records the event as processed. It returns
+// domain.ErrEventAlreadyProcessed when the event has been recorded before.
+func (r *ProcessedEventRepository) Create(ctx context.Context, event *domain.ProcessedEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	event.ProcessedAt = time.Now()
+
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrEventAlreadyProcessed
+		}
+		return fmt.Errorf("failed to record processed event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,507 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productDocument is a product as stored in the products collection. Products
+// are keyed by menu_id and id, restaurant_id is kept for lookups across menus.
+type productDocument struct {
+	MenuID         primitive.ObjectID `bson:"menu_id"`
+	RestaurantID   string             `bson:"restaurant_id"`
+	domain.Product `bson:",inline"`
+}
+
+func (r *MenuRepository) UpdateProductStatus(ctx context.Context, menuID primitive.ObjectID, productID string, status string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.touch(ctx, menuID); err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"menu_id": menuID,
+		"id":      productID,
+	}
+	update := bson.M{
+		"$set": bson.M{"status": status},
+	}
+
+	result, err := r.products.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update product status: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+// FindMenuByProductID returns the most recent menu containing the product.
+func (r *MenuRepository) FindMenuByProductID(ctx context.Context, productID string) (*domain.Menu, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	menuIDs, err := r.products.Distinct(ctx, "menu_id", bson.M{"id": productID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find menu by product: %w", err)
+	}
+
+	if len(menuIDs) == 0 {
+		return nil, domain.ErrProductNotFound
+	}
+
+	var menu domain.Menu
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	err = r.collection.FindOne(ctx, bson.M{"_id": bson.M{"$in": menuIDs}, "deleted_at": nil}, opts).Decode(&menu)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrProductNotFound
+		}
+		return nil, fmt.Errorf("failed to find menu by product: %w", err)
+	}
+
+	if err := r.loadProducts(ctx, &menu); err != nil {
+		return nil, err
+	}
+
+	return &menu, nil
+}
+
+// FindRestaurantIDsByProductID returns the restaurants having a menu, current
+// or not, with the product.
+func (r *MenuRepository) FindRestaurantIDsByProductID(ctx context.Context, productID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	values, err := r.products.Distinct(ctx, "restaurant_id", bson.M{"id": productID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find restaurants by product: %w", err)
+	}
+
+	restaurantIDs := make([]string, 0, len(values))
+	for _, value := range values {
+		if restaurantID, ok := value.(string); ok {
+			restaurantIDs = append(restaurantIDs, restaurantID)
+		}
+	}
+
+	return restaurantIDs, nil
+}
+
+// ReserveVersion bumps the version of the menu if it is still at version, for
+// a product change accepted now and written to the products later.
+func (r *MenuRepository) ReserveVersion(ctx context.Context, menuID primitive.ObjectID, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := r.bumpVersion(ctx, menuID, version, nil, domain.ErrVersionConflict)
+	return err
+}
+
+func (r *MenuRepository) AddProduct(ctx context.Context, menuID primitive.ObjectID, product *domain.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	restaurantID, err := r.touch(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	doc := productDocument{
+		MenuID:       menuID,
+		RestaurantID: restaurantID,
+		Product:      *product,
+	}
+
+	if _, err := r.products.InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrProductExists
+		}
+		return fmt.Errorf("failed to add product: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) ReplaceProduct(ctx context.Context, menuID primitive.ObjectID, product *domain.Product) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	restaurantID, err := r.touch(ctx, menuID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"menu_id": menuID,
+		"id":      product.ID,
+	}
+	doc := productDocument{
+		MenuID:       menuID,
+		RestaurantID: restaurantID,
+		Product:      *product,
+	}
+
+	result, err := r.products.ReplaceOne(ctx, filter, doc)
+	if err != nil {
+		return fmt.Errorf("failed to replace product: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) RemoveProduct(ctx context.Context, menuID primitive.ObjectID, productID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.touch(ctx, menuID); err != nil {
+		return err
+	}
+
+	result, err := r.products.DeleteOne(ctx, bson.M{"menu_id": menuID, "id": productID})
+	if err != nil {
+		return fmt.Errorf("failed to remove product: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) LinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, productID, groupID string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	groupFilter := bson.M{"attributes_groups.id": groupID}
+	if _, err := r.bumpVersion(ctx, menuID, version, groupFilter, domain.ErrAttributeGroupNotFound); err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"menu_id": menuID,
+		"id":      productID,
+	}
+	update := bson.M{
+		"$addToSet": bson.M{"attributes": groupID},
+	}
+
+	result, err := r.products.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to link attribute group: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) UnlinkAttributeGroup(ctx context.Context, menuID primitive.ObjectID, productID, groupID string, version int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.bumpVersion(ctx, menuID, version, nil, domain.ErrVersionConflict); err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"menu_id": menuID,
+		"id":      productID,
+	}
+	update := bson.M{
+		"$pull": bson.M{"attributes": groupID},
+	}
+
+	result, err := r.products.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to unlink attribute group: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrProductNotFound
+	}
+
+	return nil
+}
+
+func (r *MenuRepository) ListProducts(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter) ([]domain.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	match := productMatch(filter)
+	match["menu_id"] = menuID
+
+	products := []domain.Product{}
+	if err := r.findProducts(ctx, match, nil, filter.Limit, &products); err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	return products, nil
+}
+
+// ListProductDocuments is ListProducts limited to the given product fields.
+func (r *MenuRepository) ListProductDocuments(ctx context.Context, menuID primitive.ObjectID, filter domain.ProductFilter, fields []string) ([]bson.M, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	match := productMatch(filter)
+	match["menu_id"] = menuID
+
+	products := []bson.M{}
+	if err := r.findProducts(ctx, match, fields, filter.Limit, &products); err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	return products, nil
+}
+
+func (r *MenuRepository) SearchProducts(ctx context.Context, filter domain.ProductFilter) ([]domain.ProductSearchResult, error) {
+	menuIDs, err := r.currentMenuIDs(ctx, filter.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []domain.ProductSearchResult{}
+	if err := r.aggregate(ctx, searchProductsPipeline(menuIDs, filter, nil), &results); err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	return results, nil
+}
+
+// SearchProductDocuments is SearchProducts limited to the given product fields.
+func (r *MenuRepository) SearchProductDocuments(ctx context.Context, filter domain.ProductFilter, fields []string) ([]bson.M, error) {
+	menuIDs, err := r.currentMenuIDs(ctx, filter.RestaurantID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []bson.M{}
+	if err := r.aggregate(ctx, searchProductsPipeline(menuIDs, filter, fields), &results); err != nil {
+		return nil, fmt.Errorf("failed to search products: %w", err)
+	}
+
+	return results, nil
+}
+
+// loadProducts fills in the menu's products from the products collection.
+func (r *MenuRepository) loadProducts(ctx context.Context, menu *domain.Menu) error {
+	products := []domain.Product{}
+	if err := r.findProducts(ctx, bson.M{"menu_id": menu.ID}, nil, 0, &products); err != nil {
+		return fmt.Errorf("failed to get menu products: %w", err)
+	}
+
+	menu.Products = products
+
+	return nil
+}
+
+func (r *MenuRepository) insertProducts(ctx context.Context, menuID primitive.ObjectID, restaurantID string, products []domain.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(products))
+	for _, product := range products {
+		docs = append(docs, productDocument{
+			MenuID:       menuID,
+			RestaurantID: restaurantID,
+			Product:      product,
+		})
+	}
+
+	if _, err := r.products.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("failed to insert products: %w", err)
+	}
+
+	return nil
+}
+
+// findProducts returns products in menu order, limited to the given fields.
+// Products keep the order they were inserted in, so _id order is menu order.
+func (r *MenuRepository) findProducts(ctx context.Context, filter bson.M, fields []string, limit int, results interface{}) error {
+	opts := options.Find().
+		SetProjection(productFieldProjection(fields)).
+		SetSort(bson.D{{Key: "_id", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.products.Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+func (r *MenuRepository) aggregate(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := r.products.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	return cursor.All(ctx, results)
+}
+
+func searchProductsPipeline(menuIDs []primitive.ObjectID, filter domain.ProductFilter, fields []string) mongo.Pipeline {
+	// $text must be in the first stage
+	match := productMatch(filter)
+	match["menu_id"] = bson.M{"$in": menuIDs}
+
+	var product interface{} = "$$ROOT"
+	if len(fields) > 0 {
+		product = fieldProjection(fields, "$")
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "id", Value: 1}}}},
+	}
+	if filter.Limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: filter.Limit}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":           0,
+			"menu_id":       1,
+			"restaurant_id": 1,
+			"product":       product,
+		}}},
+		bson.D{{Key: "$lookup", Value: bson.M{
+			"from":         "menus",
+			"localField":   "menu_id",
+			"foreignField": "_id",
+			"as":           "menu",
+		}}},
+		bson.D{{Key: "$addFields", Value: bson.M{
+			"menu_name": bson.M{"$arrayElemAt": bson.A{"$menu.name", 0}},
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{"menu": 0}}},
+	)
+
+	return pipeline
+}
+
+// fieldProjection builds a projection of the given fields. With an empty
+// source the fields are included as is and _id is excluded, otherwise each one
+// is computed from source + field.
+func fieldProjection(fields []string, source string) bson.M {
+	projection := bson.M{}
+	if source == "" {
+		projection["_id"] = 0
+	}
+	for _, field := range fields {
+		if source == "" {
+			projection[field] = 1
+		} else {
+			projection[field] = source + field
+		}
+	}
+
+	return projection
+}
+
+// productFieldProjection projects stored products back to domain products,
+// limited to the given fields when there are any.
+func productFieldProjection(fields []string) bson.M {
+	if len(fields) > 0 {
+		return fieldProjection(fields, "")
+	}
+
+	return bson.M{"_id": 0, "menu_id": 0, "restaurant_id": 0}
+}
+
+// currentMenuIDs returns the latest menu of every restaurant, or of a single
+// one when restaurantID is set.
+func (r *MenuRepository) currentMenuIDs(ctx context.Context, restaurantID string) ([]primitive.ObjectID, error) {
+	match := bson.M{"deleted_at": nil}
+	if restaurantID != "" {
+		match["restaurant_id"] = restaurantID
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$restaurant_id", "menu_id": bson.M{"$first": "$_id"}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current menus: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		MenuID primitive.ObjectID `bson:"menu_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode current menus: %w", err)
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.MenuID)
+	}
+
+	return ids, nil
+}
+
+// productMatch builds a filter on stored products.
+func productMatch(filter domain.ProductFilter) bson.M {
+	match := bson.M{}
+
+	if filter.Category != "" {
+		match["category"] = filter.Category
+	}
+	if filter.Status != "" {
+		match["status"] = filter.Status
+	}
+	if filter.IsCombo != nil {
+		match["is_combo"] = *filter.IsCombo
+	}
+
+	price := bson.M{}
+	if filter.MinPrice != nil {
+		price["$gte"] = *filter.MinPrice
+	}
+	if filter.MaxPrice != nil {
+		price["$lte"] = *filter.MaxPrice
+	}
+	if len(price) > 0 {
+		match["price"] = price
+	}
+
+	// a product matches when any search word occurs in its name or
+	// description, through the products_text index
+	if strings.TrimSpace(filter.Query) != "" {
+		match["$text"] = bson.M{"$search": filter.Query}
+	}
+
+	return match
+}
@@ -12,6 +12,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// streamBatchSize is the number of audit records fetched per cursor batch
+// while streaming
+const streamBatchSize = 500
+
 type ProductStatusAuditRepository struct {
 	collection *mongo.Collection
 }
@@ -41,23 +45,194 @@ func (r *ProductStatusAuditRepository) Create(ctx context.Context, audit *domain
 	return nil
 }
 
-func (r *ProductStatusAuditRepository) GetByProductID(ctx context.Context, productID string, limit int) ([]domain.ProductStatusAudit, error) {
+// GetByProductID returns audit records of a product newest first.
+func (r *ProductStatusAuditRepository) GetByProductID(ctx context.Context, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error) {
+	query := bson.M{"product_id": filter.ProductID}
+	if filter.RestaurantID != "" {
+		query["restaurant_id"] = filter.RestaurantID
+	}
+
+	return r.find(ctx, query, filter)
+}
+
+// GetByRestaurantID returns audit records of every product and attribute of
+// a restaurant newest first.
+func (r *ProductStatusAuditRepository) GetByRestaurantID(ctx context.Context, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error) {
+	return r.find(ctx, bson.M{"restaurant_id": filter.RestaurantID}, filter)
+}
+
+// find applies the filter on top of query. Records with the same timestamp
+// are ordered by ID so pages don't overlap.
+func (r *ProductStatusAuditRepository) find(ctx context.Context, query bson.M, filter domain.ProductStatusAuditFilter) ([]domain.ProductStatusAudit, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	filter := bson.M{"product_id": productID}
-	opts := options.Find().SetSort(bson.D{{Key: "timestamp", Value: -1}}).SetLimit(int64(limit))
+	applyAuditFilter(query, filter)
+
+	if filter.AfterTimestamp != nil {
+		query["$or"] = bson.A{
+			bson.M{"timestamp": bson.M{"$lt": *filter.AfterTimestamp}},
+			bson.M{"timestamp": *filter.AfterTimestamp, "_id": bson.M{"$lt": filter.AfterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(filter.Limit))
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product status audits: %w", err)
 	}
 	defer cursor.Close(ctx)
 
-	var audits []domain.ProductStatusAudit
+	audits := []domain.ProductStatusAudit{}
 	if err := cursor.All(ctx, &audits); err != nil {
 		return nil, fmt.Errorf("failed to decode product status audits: %w", err)
 	}
 
 	return audits, nil
 }
+
+// Stream calls fn with every audit record matching the filter, oldest first.
+// Records are read through a cursor, so any number of them can be streamed.
+// ProductID and RestaurantID are applied when set, cursor fields and Limit
+// are ignored.
+func (r *ProductStatusAuditRepository) Stream(ctx context.Context, filter domain.ProductStatusAuditFilter, fn func(audit domain.ProductStatusAudit) error) error {
+	query := bson.M{}
+	if filter.ProductID != "" {
+		query["product_id"] = filter.ProductID
+	}
+	if filter.RestaurantID != "" {
+		query["restaurant_id"] = filter.RestaurantID
+	}
+	applyAuditFilter(query, filter)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}, {Key: "_id", Value: 1}}).
+		SetBatchSize(streamBatchSize)
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return fmt.Errorf("failed to stream product status audits: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var audit domain.ProductStatusAudit
+		if err := cursor.Decode(&audit); err != nil {
+			return fmt.Errorf("failed to decode product status audit: %w", err)
+		}
+		if err := fn(audit); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate product status audits: %w", err)
+	}
+
+	return nil
+}
+
+func (r *ProductStatusAuditRepository) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete product status audits: %w", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
+// Upsert writes audit records by ID, so records that are already stored are
+// replaced rather than duplicated.
+func (r *ProductStatusAuditRepository) Upsert(ctx context.Context, audits []domain.ProductStatusAudit) error {
+	if len(audits) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	models := make([]mongo.WriteModel, 0, len(audits))
+	for _, audit := range audits {
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": audit.ID}).
+			SetReplacement(audit).
+			SetUpsert(true))
+	}
+
+	if _, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to upsert product status audits: %w", err)
+	}
+
+	return nil
+}
+
+func applyAuditFilter(query bson.M, filter domain.ProductStatusAuditFilter) {
+	if filter.EventType != "" {
+		query["event_type"] = filter.EventType
+	}
+	if filter.UserID != "" {
+		query["user_id"] = filter.UserID
+	}
+	if filter.Reason != "" {
+		query["reason"] = filter.Reason
+	}
+
+	timestamp := bson.M{}
+	if filter.From != nil {
+		timestamp["$gte"] = *filter.From
+	}
+	if filter.To != nil {
+		timestamp["$lt"] = *filter.To
+	}
+	if len(timestamp) > 0 {
+		query["timestamp"] = timestamp
+	}
+}
+
+// GetLatestByProductIDs returns the latest audit record of each product of the
+// restaurant. Records written before audits were scoped to a restaurant count
+// as well, branch status overrides and rejected changes don't.
+func (r *ProductStatusAuditRepository) GetLatestByProductIDs(ctx context.Context, restaurantID string, productIDs []string) (map[string]domain.ProductStatusAudit, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"product_id":    bson.M{"$in": productIDs},
+			"restaurant_id": bson.M{"$in": bson.A{restaurantID, nil}},
+			"branch_id":     nil,
+			"rejected":      nil,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "timestamp", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$product_id",
+			"audit": bson.M{"$first": "$$ROOT"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest product status audits: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Audit domain.ProductStatusAudit `bson:"audit"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode product status audits: %w", err)
+	}
+
+	audits := make(map[string]domain.ProductStatusAudit, len(rows))
+	for _, row := range rows {
+		audits[row.Audit.ProductID] = row.Audit
+	}
+
+	return audits, nil
+}
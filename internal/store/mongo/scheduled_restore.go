@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ScheduledRestoreRepository struct {
+	collection *mongo.Collection
+}
+
+func NewScheduledRestoreRepository(db *mongo.Database) *ScheduledRestoreRepository {
+	return &ScheduledRestoreRepository{
+		collection: db.Collection("scheduled_restores"),
+	}
+}
+
+func (r *ScheduledRestoreRepository) Create(ctx context.Context, restore *domain.ScheduledRestore) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if restore.ID.IsZero() {
+		restore.ID = primitive.NewObjectID()
+	}
+	restore.State = domain.RestorePending
+	restore.CreatedAt = time.Now()
+	restore.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, restore)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduled restore: %w", err)
+	}
+
+	return nil
+}
+
+// CancelPending cancels restores of the product that have not fired yet.
+// With a branchID only the restores of that branch are canceled, otherwise
+// the restores of the restaurant and all its branches.
+func (r *ScheduledRestoreRepository) CancelPending(ctx context.Context, restaurantID, branchID, productID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"restaurant_id": restaurantID,
+		"product_id":    productID,
+		"state":         domain.RestorePending,
+	}
+	if branchID != "" {
+		filter["branch_id"] = branchID
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"state":      domain.RestoreCanceled,
+			"updated_at": time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateMany(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to cancel scheduled restores: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDue atomically moves the earliest due pending restore to firing for
+// lease and returns it, or nil when nothing is due. A restore can be claimed
+// only once, so concurrent instances never fire the same restore. A restore
+// whose scheduler died before completing it is claimed again when the lease
+// ends. The lease is counted from now, the same clock due restores and
+// expired leases are matched with.
+func (r *ScheduledRestoreRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*domain.ScheduledRestore, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// restores claimed before claims had a lease have no claimed_until
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"state": domain.RestorePending, "run_at": bson.M{"$lte": now}},
+			bson.M{"state": domain.RestoreFiring, "claimed_until": bson.M{"$not": bson.M{"$gt": now}}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"state":         domain.RestoreFiring,
+			"claimed_until": now.Add(lease),
+			"updated_at":    now,
+		},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "run_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var restore domain.ScheduledRestore
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restore)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim scheduled restore: %w", err)
+	}
+
+	return &restore, nil
+}
+
+// Complete finishes a claimed restore with the given state.
+func (r *ScheduledRestoreRepository) Complete(ctx context.Context, id primitive.ObjectID, state string) error {
+	return r.moveFiring(ctx, id, bson.M{"state": state})
+}
+
+// Release returns a claimed restore to pending, to be retried at runAt.
+func (r *ScheduledRestoreRepository) Release(ctx context.Context, id primitive.ObjectID, runAt time.Time) error {
+	return r.moveFiring(ctx, id, bson.M{"state": domain.RestorePending, "run_at": runAt})
+}
+
+func (r *ScheduledRestoreRepository) moveFiring(ctx context.Context, id primitive.ObjectID, set bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"_id":   id,
+		"state": domain.RestoreFiring,
+	}
+	set["updated_at"] = time.Now()
+	update := bson.M{
+		"$set":   set,
+		"$unset": bson.M{"claimed_until": ""},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled restore: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("scheduled restore %s is not claimed", id.Hex())
+	}
+
+	return nil
+}
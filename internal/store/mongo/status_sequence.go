@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type StatusSequenceRepository struct {
+	collection *mongo.Collection
+}
+
+func NewStatusSequenceRepository(db *mongo.Database) *StatusSequenceRepository {
+	return &StatusSequenceRepository{
+		collection: db.Collection("status_sequences"),
+	}
+}
+
+// Next issues the next sequence number of the entity's status changes,
+// starting from 1.
+func (r *StatusSequenceRepository) Next(ctx context.Context, restaurantID, entityType, entityID string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$inc": bson.M{"issued": 1},
+		"$setOnInsert": bson.M{
+			"applied":     0,
+			"applied_max": 0,
+			"branches":    bson.A{},
+		},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	// concurrent upserts of a new entity race on the unique index, the loser
+	// increments the document the winner inserted
+	for attempt := 0; attempt < 2; attempt++ {
+		var sequence domain.StatusSequence
+		err := r.collection.FindOneAndUpdate(ctx, sequenceFilter(restaurantID, entityType, entityID), update, opts).Decode(&sequence)
+		if err == nil {
+			return sequence.Issued, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, fmt.Errorf("failed to issue status sequence: %w", err)
+		}
+	}
+
+	return 0, fmt.Errorf("failed to issue status sequence: concurrent inserts of %s %q", entityType, entityID)
+}
+
+// Get returns the entity's sequence, or an empty one if no change of it has
+// been numbered yet.
+func (r *StatusSequenceRepository) Get(ctx context.Context, restaurantID, entityType, entityID string) (*domain.StatusSequence, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var sequence domain.StatusSequence
+	err := r.collection.FindOne(ctx, sequenceFilter(restaurantID, entityType, entityID)).Decode(&sequence)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &domain.StatusSequence{
+				RestaurantID: restaurantID,
+				EntityType:   entityType,
+				EntityID:     entityID,
+				Branches:     []domain.BranchSequence{},
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to get status sequence: %w", err)
+	}
+
+	return &sequence, nil
+}
+
+// SaveApplied stores the applied changes of the sequence. The issued number
+// is left as is, it may have moved on since the sequence was read.
+func (r *StatusSequenceRepository) SaveApplied(ctx context.Context, sequence *domain.StatusSequence) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"applied":     sequence.Applied,
+			"applied_max": sequence.AppliedMax,
+			"branches":    sequence.Branches,
+		},
+		"$setOnInsert": bson.M{"issued": sequence.AppliedMax},
+	}
+	filter := sequenceFilter(sequence.RestaurantID, sequence.EntityType, sequence.EntityID)
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true)); err != nil {
+		return fmt.Errorf("failed to save status sequence: %w", err)
+	}
+
+	return nil
+}
+
+func sequenceFilter(restaurantID, entityType, entityID string) bson.M {
+	return bson.M{
+		"restaurant_id": restaurantID,
+		"entity_type":   entityType,
+		"entity_id":     entityID,
+	}
+}
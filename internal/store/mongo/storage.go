@@ -66,8 +66,25 @@ func (s *Storage) Client() *mongo.Client {
 	return s.client
 }
 
-func (s *Storage) StartSession() (mongo.Session, error) {
-	return s.client.StartSession()
+// WithTransaction runs fn in a transaction and commits it when fn returns
+// nil. Repositories must be called with the context passed to fn, writes made
+// with any other context are not part of the transaction. The whole
+// transaction is retried on TransientTransactionError, such as a write
+// conflict, and the commit on UnknownTransactionCommitResult, so fn may run
+// more than once and must not keep state between runs. Transactions need
+// MongoDB running as a replica set.
+func (s *Storage) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := s.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+
+	return err
 }
 
 func (s *Storage) CreateIndexes(ctx context.Context) error {
@@ -77,13 +94,36 @@ func (s *Storage) CreateIndexes(ctx context.Context) error {
 			Keys: bson.D{{Key: "restaurant_id", Value: 1}},
 		},
 		{
-			Keys: bson.D{{Key: "products.id", Value: 1}},
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
 		},
 	}
 	if _, err := s.database.Collection("menus").Indexes().CreateMany(ctx, menusIndexes); err != nil {
 		return fmt.Errorf("failed to create menus indexes: %w", err)
 	}
 
+	// create indexes for products collection
+	productsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "menu_id", Value: 1}, {Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "id", Value: 1}, {Key: "restaurant_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "description", Value: "text"}},
+			// menus are multilingual, so skip stemming and stop words
+			Options: options.Index().SetDefaultLanguage("none").SetName("products_text"),
+		},
+	}
+	if _, err := s.database.Collection("products").Indexes().CreateMany(ctx, productsIndexes); err != nil {
+		return fmt.Errorf("failed to create products indexes: %w", err)
+	}
+
 	// create indexes for parsing_tasks collection
 	tasksIndexes := []mongo.IndexModel{
 		{
@@ -100,7 +140,13 @@ func (s *Storage) CreateIndexes(ctx context.Context) error {
 	// create indexes for product_status_audit collection
 	auditIndexes := []mongo.IndexModel{
 		{
-			Keys: bson.D{{Key: "product_id", Value: 1}},
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "product_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "timestamp", Value: -1}},
 		},
 		{
 			Keys: bson.D{{Key: "timestamp", Value: 1}},
@@ -110,5 +156,113 @@ func (s *Storage) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create product_status_audit indexes: %w", err)
 	}
 
+	// create indexes for audit_log collection
+	auditLogIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "actor", Value: 1}, {Key: "timestamp", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "timestamp", Value: -1}},
+		},
+	}
+	if _, err := s.database.Collection("audit_log").Indexes().CreateMany(ctx, auditLogIndexes); err != nil {
+		return fmt.Errorf("failed to create audit_log indexes: %w", err)
+	}
+
+	// create indexes for scheduled_restores collection
+	restoresIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "state", Value: 1}, {Key: "run_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "product_id", Value: 1}, {Key: "state", Value: 1}},
+		},
+	}
+	if _, err := s.database.Collection("scheduled_restores").Indexes().CreateMany(ctx, restoresIndexes); err != nil {
+		return fmt.Errorf("failed to create scheduled_restores indexes: %w", err)
+	}
+
+	// create indexes for outbox collection, sent messages are kept for a week
+	outboxIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "state", Value: 1}, {Key: "available_at", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "sent_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	}
+	if _, err := s.database.Collection("outbox").Indexes().CreateMany(ctx, outboxIndexes); err != nil {
+		return fmt.Errorf("failed to create outbox indexes: %w", err)
+	}
+
+	// create indexes for status_sequences collection
+	statusSequencesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "restaurant_id", Value: 1}, {Key: "entity_type", Value: 1}, {Key: "entity_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := s.database.Collection("status_sequences").Indexes().CreateMany(ctx, statusSequencesIndexes); err != nil {
+		return fmt.Errorf("failed to create status_sequences indexes: %w", err)
+	}
+
+	// processed events only need to outlive redeliveries, they are kept for
+	// a week
+	processedEventsIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "processed_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(7 * 24 * 60 * 60),
+		},
+	}
+	if _, err := s.database.Collection("processed_events").Indexes().CreateMany(ctx, processedEventsIndexes); err != nil {
+		return fmt.Errorf("failed to create processed_events indexes: %w", err)
+	}
+
+	// create indexes for webhooks collection
+	webhooksIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "restaurant_id", Value: 1}, {Key: "active", Value: 1}},
+		},
+	}
+	if _, err := s.database.Collection("webhooks").Indexes().CreateMany(ctx, webhooksIndexes); err != nil {
+		return fmt.Errorf("failed to create webhooks indexes: %w", err)
+	}
+
+	// create indexes for webhook_deliveries collection, the delivery log is
+	// kept for 30 days
+	webhookDeliveriesIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "state", Value: 1}, {Key: "next_attempt_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	}
+	if _, err := s.database.Collection("webhook_deliveries").Indexes().CreateMany(ctx, webhookDeliveriesIndexes); err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries indexes: %w", err)
+	}
+
+	// create indexes for branches collection
+	branchesIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "restaurant_id", Value: 1}, {Key: "id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+	if _, err := s.database.Collection("branches").Indexes().CreateMany(ctx, branchesIndexes); err != nil {
+		return fmt.Errorf("failed to create branches indexes: %w", err)
+	}
+
 	return nil
 }
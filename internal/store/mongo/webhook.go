@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookRepository(db *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		collection: db.Collection("webhooks"),
+	}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if webhook.ID.IsZero() {
+		webhook.ID = primitive.NewObjectID()
+	}
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = webhook.CreatedAt
+
+	if _, err := r.collection.InsertOne(ctx, webhook); err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, restaurantID string, id primitive.ObjectID) (*domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var webhook domain.Webhook
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "restaurant_id": restaurantID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrWebhookNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (r *WebhookRepository) ListByRestaurantID(ctx context.Context, restaurantID string) ([]domain.Webhook, error) {
+	return r.find(ctx, bson.M{"restaurant_id": restaurantID})
+}
+
+// ListSubscribed returns the active webhooks of the restaurant subscribed to
+// the event type, including those subscribed to all events.
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, restaurantID, eventType string) ([]domain.Webhook, error) {
+	return r.find(ctx, bson.M{
+		"restaurant_id": restaurantID,
+		"active":        true,
+		"$or": bson.A{
+			bson.M{"event_types": eventType},
+			bson.M{"event_types": bson.A{}},
+		},
+	})
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, webhook *domain.Webhook) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	webhook.UpdatedAt = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"url":         webhook.URL,
+			"event_types": webhook.EventTypes,
+			"secret":      webhook.Secret,
+			"active":      webhook.Active,
+			"updated_at":  webhook.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": webhook.ID, "restaurant_id": webhook.RestaurantID}, update)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, restaurantID string, id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "restaurant_id": restaurantID})
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return domain.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) find(ctx context.Context, filter bson.M) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []domain.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, fmt.Errorf("failed to decode webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
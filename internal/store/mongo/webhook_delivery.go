@@ -0,0 +1,136 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WebhookDeliveryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewWebhookDeliveryRepository(db *mongo.Database) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		collection: db.Collection("webhook_deliveries"),
+	}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	delivery.State = domain.WebhookDeliveryPending
+	delivery.Log = []domain.WebhookAttempt{}
+	delivery.CreatedAt = time.Now()
+	delivery.NextAttemptAt = delivery.CreatedAt
+
+	if _, err := r.collection.InsertOne(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimNext atomically claims the pending delivery that has waited longest
+// for its next attempt at now for lease, or returns nil when there is none.
+// A delivery whose dispatcher died mid-attempt is attempted again when the
+// lease ends.
+func (r *WebhookDeliveryRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"state":           domain.WebhookDeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery domain.WebhookDelivery
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// RecordAttempt appends the attempt to the delivery log and stores the state,
+// attempt count, last error and next attempt time of the delivery.
+func (r *WebhookDeliveryRepository) RecordAttempt(ctx context.Context, delivery *domain.WebhookDelivery, attempt domain.WebhookAttempt) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	set := bson.M{
+		"state":           delivery.State,
+		"attempts":        delivery.Attempts,
+		"next_attempt_at": delivery.NextAttemptAt,
+	}
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"log": attempt},
+	}
+	if delivery.LastError != "" {
+		set["last_error"] = delivery.LastError
+	} else {
+		update["$unset"] = bson.M{"last_error": ""}
+	}
+	if delivery.DeliveredAt != nil {
+		set["delivered_at"] = *delivery.DeliveredAt
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, delivery.ID, update); err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) Find(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := bson.M{"webhook_id": filter.WebhookID}
+	if filter.State != "" {
+		query["state"] = filter.State
+	}
+
+	if filter.AfterTimestamp != nil {
+		query["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$lt": *filter.AfterTimestamp}},
+			bson.M{"created_at": *filter.AfterTimestamp, "_id": bson.M{"$lt": filter.AfterID}},
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(filter.Limit))
+
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []domain.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
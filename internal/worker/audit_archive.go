@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"go.uber.org/zap"
+)
+
+// AuditArchiveWorker periodically archives product status audit records older
+// than the retention period.
+type AuditArchiveWorker struct {
+	archiveService *service.AuditArchiveService
+	retention      time.Duration
+	interval       time.Duration
+	logger         *zap.SugaredLogger
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+func NewAuditArchiveWorker(
+	archiveService *service.AuditArchiveService,
+	retention time.Duration,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+) *AuditArchiveWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &AuditArchiveWorker{
+		archiveService: archiveService,
+		retention:      retention,
+		interval:       interval,
+		logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (w *AuditArchiveWorker) Start() error {
+	w.logger.Infow("starting audit archive worker", "retention", w.retention.String(), "interval", w.interval.String())
+
+	go w.run()
+
+	return nil
+}
+
+func (w *AuditArchiveWorker) Stop() {
+	w.logger.Info("stopping audit archive worker")
+	w.cancel()
+}
+
+func (w *AuditArchiveWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.archive()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *AuditArchiveWorker) archive() {
+	path, deleted, err := w.archiveService.ArchiveBefore(w.ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		w.logger.Errorw("failed to archive audit records", "path", path, "deleted", deleted, "error", err)
+		return
+	}
+
+	if deleted > 0 {
+		w.logger.Infow("audit records archived", "path", path, "deleted", deleted)
+	}
+}
@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"go.uber.org/zap"
+)
+
+// MenuPurgeWorker periodically purges menus that were soft-deleted longer
+// than the retention period ago.
+type MenuPurgeWorker struct {
+	menuService *service.MenuService
+	retention   time.Duration
+	interval    time.Duration
+	logger      *zap.SugaredLogger
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+func NewMenuPurgeWorker(
+	menuService *service.MenuService,
+	retention time.Duration,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+) *MenuPurgeWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &MenuPurgeWorker{
+		menuService: menuService,
+		retention:   retention,
+		interval:    interval,
+		logger:      logger,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+}
+
+func (w *MenuPurgeWorker) Start() error {
+	w.logger.Infow("starting menu purge worker", "retention", w.retention.String(), "interval", w.interval.String())
+
+	go w.run()
+
+	return nil
+}
+
+func (w *MenuPurgeWorker) Stop() {
+	w.logger.Info("stopping menu purge worker")
+	w.cancel()
+}
+
+func (w *MenuPurgeWorker) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.purge()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *MenuPurgeWorker) purge() {
+	purged, err := w.menuService.PurgeDeletedMenus(w.ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		w.logger.Errorw("failed to purge deleted menus", "purged", purged, "error", err)
+		return
+	}
+
+	if purged > 0 {
+		w.logger.Infow("deleted menus purged", "purged", purged)
+	}
+}
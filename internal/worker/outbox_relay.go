@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"go.uber.org/zap"
+)
+
+// OutboxRelay periodically publishes pending outbox messages to the broker.
+// Messages are claimed one at a time, so several instances can run the relay.
+type OutboxRelay struct {
+	outboxService *service.OutboxService
+	interval      time.Duration
+	logger        *zap.SugaredLogger
+	ctx           context.Context
+	cancel        context.CancelFunc
+}
+
+func NewOutboxRelay(
+	outboxService *service.OutboxService,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+) *OutboxRelay {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &OutboxRelay{
+		outboxService: outboxService,
+		interval:      interval,
+		logger:        logger,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+}
+
+func (w *OutboxRelay) Start() error {
+	w.logger.Infow("starting outbox relay", "interval", w.interval.String())
+
+	go w.run()
+
+	return nil
+}
+
+func (w *OutboxRelay) Stop() {
+	w.logger.Info("stopping outbox relay")
+	w.cancel()
+}
+
+func (w *OutboxRelay) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.relay()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *OutboxRelay) relay() {
+	sent, err := w.outboxService.RelayPending(w.ctx, time.Now())
+	if err != nil {
+		w.logger.Errorw("failed to relay outbox messages", "sent", sent, "error", err)
+		return
+	}
+
+	if sent > 0 {
+		w.logger.Debugw("outbox messages relayed", "sent", sent)
+	}
+}
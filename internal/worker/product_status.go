@@ -3,6 +3,7 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -58,10 +59,15 @@ func (w *ProductStatusWorker) handleMessage(ctx context.Context, message []byte)
 		event.Timestamp = time.Now()
 	}
 
-	w.logger.Infow("processing product status event", "product_id", event.ProductID, "event_type", event.EventType)
+	w.logger.Infow("processing product status event", "event_id", event.ID, "restaurant_id", event.RestaurantID, "product_id", event.ProductID, "event_type", event.EventType, "items", len(event.Items))
 
 	if err := w.productService.ProcessProductStatusEvent(ctx, event); err != nil {
-		w.logger.Errorw("failed to process product status event", "product_id", event.ProductID, "error", err)
+		// a redelivered copy of an event that has been applied
+		if errors.Is(err, domain.ErrEventAlreadyProcessed) {
+			w.logger.Infow("skipping already processed product event", "event_id", event.ID, "product_id", event.ProductID, "event_type", event.EventType)
+			return nil
+		}
+		w.logger.Errorw("failed to process product status event", "restaurant_id", event.RestaurantID, "product_id", event.ProductID, "error", err)
 		return err
 	}
 
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"go.uber.org/zap"
+)
+
+// RestoreScheduler periodically fires due scheduled product status restores.
+// Restores are stored in MongoDB, so they survive restarts, and claimed one
+// at a time, so several instances can run the scheduler.
+type RestoreScheduler struct {
+	productService *service.ProductService
+	interval       time.Duration
+	logger         *zap.SugaredLogger
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+func NewRestoreScheduler(
+	productService *service.ProductService,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+) *RestoreScheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &RestoreScheduler{
+		productService: productService,
+		interval:       interval,
+		logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (w *RestoreScheduler) Start() error {
+	w.logger.Infow("starting restore scheduler", "interval", w.interval.String())
+
+	go w.run()
+
+	return nil
+}
+
+func (w *RestoreScheduler) Stop() {
+	w.logger.Info("stopping restore scheduler")
+	w.cancel()
+}
+
+func (w *RestoreScheduler) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.fire()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *RestoreScheduler) fire() {
+	fired, err := w.productService.RunScheduledRestores(w.ctx, time.Now())
+	if err != nil {
+		w.logger.Errorw("failed to run scheduled restores", "fired", fired, "error", err)
+		return
+	}
+
+	if fired > 0 {
+		w.logger.Infow("scheduled restores fired", "fired", fired)
+	}
+}
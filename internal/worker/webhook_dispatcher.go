@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/Beka01247/kwaaka-tz/internal/service"
+	"go.uber.org/zap"
+)
+
+// WebhookDispatcher periodically sends queued webhook deliveries. Deliveries
+// are claimed one at a time, so several instances can run the dispatcher.
+type WebhookDispatcher struct {
+	webhookService *service.WebhookService
+	interval       time.Duration
+	logger         *zap.SugaredLogger
+	ctx            context.Context
+	cancel         context.CancelFunc
+}
+
+func NewWebhookDispatcher(
+	webhookService *service.WebhookService,
+	interval time.Duration,
+	logger *zap.SugaredLogger,
+) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &WebhookDispatcher{
+		webhookService: webhookService,
+		interval:       interval,
+		logger:         logger,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+func (w *WebhookDispatcher) Start() error {
+	w.logger.Infow("starting webhook dispatcher", "interval", w.interval.String())
+
+	go w.run()
+
+	return nil
+}
+
+func (w *WebhookDispatcher) Stop() {
+	w.logger.Info("stopping webhook dispatcher")
+	w.cancel()
+}
+
+func (w *WebhookDispatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.dispatch()
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WebhookDispatcher) dispatch() {
+	delivered, err := w.webhookService.DeliverPending(w.ctx, time.Now())
+	if err != nil {
+		w.logger.Errorw("failed to dispatch webhook deliveries", "delivered", delivered, "error", err)
+		return
+	}
+
+	if delivered > 0 {
+		w.logger.Debugw("webhook deliveries sent", "delivered", delivered)
+	}
+}